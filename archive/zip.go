@@ -0,0 +1,275 @@
+package archive
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// ManifestName The entry which records the SHA-256 of every file in the zip, in sha256sum format
+	ManifestName = "manifest.sha256"
+)
+
+// DefaultModTime The modified time of all entries, fixed so the same files always make the same zip
+var DefaultModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// Option The option of the zip writer
+type Option func(w *Writer)
+
+// WithLevel Set the deflate level, from flate.NoCompression to flate.BestCompression
+func WithLevel(level int) Option {
+	return func(w *Writer) {
+		w.level = level
+	}
+}
+
+// WithModTime Set the modified time of all entries
+func WithModTime(t time.Time) Option {
+	return func(w *Writer) {
+		w.modTime = t
+	}
+}
+
+// Writer Stream files into a zip, every file's SHA-256 is recorded into the manifest entry when closing
+type Writer struct {
+	zw      *zip.Writer
+	level   int
+	modTime time.Time
+	sums    map[string]string
+}
+
+// NewWriter Create a zip writer writing to w
+func NewWriter(w io.Writer, opts ...Option) *Writer {
+	aw := &Writer{
+		zw:      zip.NewWriter(w),
+		level:   flate.DefaultCompression,
+		modTime: DefaultModTime,
+		sums:    map[string]string{},
+	}
+	for _, opt := range opts {
+		opt(aw)
+	}
+	level := aw.level
+	aw.zw.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, level)
+	})
+	return aw
+}
+
+// create Create the entry with the fixed header
+func (w *Writer) create(name string) (io.Writer, error) {
+	header := &zip.FileHeader{
+		Name:     name,
+		Method:   zip.Deflate,
+		Modified: w.modTime,
+	}
+	header.SetMode(0644)
+	return w.zw.CreateHeader(header)
+}
+
+// AddReader Stream the reader into the zip as the entry name
+func (w *Writer) AddReader(name string, r io.Reader) error {
+	name = path.Clean(filepath.ToSlash(name))
+	if name == ManifestName {
+		return fmt.Errorf("the entry name %s is reserved", ManifestName)
+	}
+	if _, ok := w.sums[name]; ok {
+		return fmt.Errorf("duplicate entry %s", name)
+	}
+
+	entry, err := w.create(name)
+	if err != nil {
+		return err
+	}
+	h := sha256.New()
+	if _, err = io.Copy(io.MultiWriter(entry, h), r); err != nil {
+		return err
+	}
+	w.sums[name] = hex.EncodeToString(h.Sum(nil))
+	return nil
+}
+
+// AddFile Stream the file into the zip as the entry name
+func (w *Writer) AddFile(name string, filePath string) error {
+	f, err := os.Open(filePath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return w.AddReader(name, f)
+}
+
+// Close Write the manifest entry and finish the zip
+func (w *Writer) Close() error {
+	names := make([]string, 0, len(w.sums))
+	for name := range w.sums {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	entry, err := w.create(ManifestName)
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		if _, err = fmt.Fprintf(entry, "%s  %s\n", w.sums[name], name); err != nil {
+			return err
+		}
+	}
+	return w.zw.Close()
+}
+
+// ZipDir Compress the files under the directory into the target zip, keep the directory structure.
+// The entries are sorted by path, so the same files always make the same zip.
+func ZipDir(srcDir string, target string, opts ...Option) error {
+	s, err := os.Stat(srcDir)
+	if err != nil {
+		return fmt.Errorf("the source directory %s not exists", srcDir)
+	}
+	if !s.IsDir() {
+		return fmt.Errorf("the source %s is not a directory", srcDir)
+	}
+
+	var files []string
+	err = filepath.Walk(srcDir, func(filePath string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.Mode().IsRegular() {
+			files = append(files, filePath)
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	out, err := os.Create(target)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	w := NewWriter(out, opts...)
+	for _, file := range files {
+		name, err := filepath.Rel(srcDir, file)
+		if err != nil {
+			return err
+		}
+		if err = w.AddFile(name, file); err != nil {
+			return err
+		}
+	}
+	if err = w.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// Verify Check every file in the zip against the manifest entry.
+// Returns an error if the manifest is missing, a file is missing, not recorded, or its SHA-256 mismatches.
+func Verify(zipPath string) error {
+	r, err := zip.OpenReader(zipPath)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	entries := map[string]*zip.File{}
+	var manifest *zip.File
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		if f.Name == ManifestName {
+			manifest = f
+			continue
+		}
+		entries[f.Name] = f
+	}
+	if manifest == nil {
+		return fmt.Errorf("%s has no %s", zipPath, ManifestName)
+	}
+
+	sums, err := readManifest(manifest)
+	if err != nil {
+		return err
+	}
+
+	var problems []string
+	for name, sum := range sums {
+		f, ok := entries[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s missing", name))
+			continue
+		}
+		actual, err := sha256OfEntry(f)
+		if err != nil {
+			return err
+		}
+		if actual != sum {
+			problems = append(problems, fmt.Sprintf("%s checksum mismatch", name))
+		}
+	}
+	for name := range entries {
+		if _, ok := sums[name]; !ok {
+			problems = append(problems, fmt.Sprintf("%s not in manifest", name))
+		}
+	}
+	if len(problems) > 0 {
+		sort.Strings(problems)
+		return fmt.Errorf("%s verify failed: %s", zipPath, strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// readManifest Read the SHA-256 of every file from the manifest entry
+func readManifest(f *zip.File) (map[string]string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return nil, err
+	}
+	defer rc.Close()
+	content, err := io.ReadAll(rc)
+	if err != nil {
+		return nil, err
+	}
+
+	sums := map[string]string{}
+	for _, line := range strings.Split(string(content), "\n") {
+		if line == "" {
+			continue
+		}
+		parts := strings.SplitN(line, "  ", 2)
+		if len(parts) != 2 {
+			return nil, fmt.Errorf("invalid manifest line: %q", line)
+		}
+		sums[parts[1]] = parts[0]
+	}
+	return sums, nil
+}
+
+// sha256OfEntry The SHA-256 of the entry content
+func sha256OfEntry(f *zip.File) (string, error) {
+	rc, err := f.Open()
+	if err != nil {
+		return "", err
+	}
+	defer rc.Close()
+	h := sha256.New()
+	if _, err = io.Copy(h, rc); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
@@ -0,0 +1,150 @@
+package archive
+
+import (
+	"archive/zip"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+// testFiles The files of the source directory, keyed by the entry name
+var testFiles = map[string]string{
+	"vat-note/C1.pdf":     "%PDF-1.4 vat note of C1",
+	"vat-note/C2.pdf":     "%PDF-1.4 vat note of C2",
+	"transfer-doc/C1.pdf": "%PDF-1.4 transfer doc of C1",
+	"manifest.json":       `{"failed_total":0}`,
+}
+
+// writeTestDir Write the test files into a new directory, the files get the modified time
+func writeTestDir(t *testing.T, modTime time.Time) string {
+	t.Helper()
+	dir := t.TempDir()
+	for name, content := range testFiles {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.Chtimes(p, modTime, modTime); err != nil {
+			t.Fatal(err)
+		}
+	}
+	return dir
+}
+
+func TestZipDirIsReproducible(t *testing.T) {
+	// 两次的源目录不同、文件时间不同，生成的压缩包应逐字节相同
+	first := filepath.Join(t.TempDir(), "first.zip")
+	if err := ZipDir(writeTestDir(t, time.Date(2024, 9, 1, 8, 0, 0, 0, time.UTC)), first); err != nil {
+		t.Fatalf("ZipDir error = %v", err)
+	}
+	second := filepath.Join(t.TempDir(), "second.zip")
+	if err := ZipDir(writeTestDir(t, time.Now()), second); err != nil {
+		t.Fatalf("ZipDir error = %v", err)
+	}
+
+	a, err := os.ReadFile(first)
+	if err != nil {
+		t.Fatal(err)
+	}
+	b, err := os.ReadFile(second)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(a, b) {
+		t.Errorf("the zips of the same files differ: %d bytes and %d bytes", len(a), len(b))
+	}
+}
+
+func TestZipDirManifest(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "vatnote.zip")
+	if err := ZipDir(writeTestDir(t, time.Now()), target); err != nil {
+		t.Fatalf("ZipDir error = %v", err)
+	}
+
+	r, err := zip.OpenReader(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer r.Close()
+
+	var manifest *zip.File
+	var names []string
+	for _, f := range r.File {
+		if f.Name == ManifestName {
+			manifest = f
+			continue
+		}
+		names = append(names, f.Name)
+		if !f.Modified.Equal(DefaultModTime) {
+			t.Errorf("%s modified = %v, want %v", f.Name, f.Modified, DefaultModTime)
+		}
+	}
+	if manifest == nil {
+		t.Fatalf("no %s in the zip", ManifestName)
+	}
+	if want := "manifest.json,transfer-doc/C1.pdf,vat-note/C1.pdf,vat-note/C2.pdf"; strings.Join(names, ",") != want {
+		t.Errorf("entries = %v, want sorted %s", names, want)
+	}
+
+	sums, err := readManifest(manifest)
+	if err != nil {
+		t.Fatalf("readManifest error = %v", err)
+	}
+	if len(sums) != len(testFiles) {
+		t.Errorf("manifest has %d files, want %d", len(sums), len(testFiles))
+	}
+	for name, content := range testFiles {
+		h := sha256.Sum256([]byte(content))
+		if want := hex.EncodeToString(h[:]); sums[name] != want {
+			t.Errorf("manifest sha256 of %s = %s, want %s", name, sums[name], want)
+		}
+	}
+
+	if err = Verify(target); err != nil {
+		t.Errorf("Verify error = %v", err)
+	}
+}
+
+func TestVerifyChecksumMismatch(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "tampered.zip")
+	out, err := os.Create(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	zw := zip.NewWriter(out)
+	entry, _ := zw.Create("a.pdf")
+	_, _ = io.WriteString(entry, "changed content")
+	h := sha256.Sum256([]byte("original content"))
+	entry, _ = zw.Create(ManifestName)
+	_, _ = io.WriteString(entry, hex.EncodeToString(h[:])+"  a.pdf\n")
+	if err = zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	_ = out.Close()
+
+	if err = Verify(target); err == nil || !strings.Contains(err.Error(), "a.pdf checksum mismatch") {
+		t.Errorf("Verify error = %v, want checksum mismatch of a.pdf", err)
+	}
+}
+
+func TestAddReaderRejectsReservedAndDuplicateNames(t *testing.T) {
+	w := NewWriter(io.Discard)
+	if err := w.AddReader(ManifestName, strings.NewReader("x")); err == nil {
+		t.Errorf("AddReader(%s) want error", ManifestName)
+	}
+	if err := w.AddReader("a/b.pdf", strings.NewReader("x")); err != nil {
+		t.Fatalf("AddReader error = %v", err)
+	}
+	if err := w.AddReader("a/./b.pdf", strings.NewReader("y")); err == nil {
+		t.Errorf("AddReader of duplicate entry want error")
+	}
+}
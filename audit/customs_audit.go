@@ -1,7 +1,9 @@
 package audit
 
 import (
+	"database/sql"
 	"fmt"
+	"github.com/jmoiron/sqlx"
 	"github.com/labstack/gommon/log"
 	"github.com/spf13/viper"
 	"github.com/xuri/excelize/v2"
@@ -9,44 +11,106 @@ import (
 	_ "image/jpeg"
 	_ "image/png"
 	"path/filepath"
-	"strings"
 	"sysafari.com/customs/tguard/global"
-	"sysafari.com/customs/tguard/oss"
 	"sysafari.com/customs/tguard/utils"
+	"sysafari.com/customs/tguard/workbook"
+	"time"
 )
 
 type CustomsAudit struct {
 	// Month exp: 2006-01
 	Month string `json:"month"`
+	// From, To The submitted date range, exp: 2006-01-02, both are included
+	From string `json:"from"`
+	To   string `json:"to"`
+	// DutyParty Audit the customs of the duty party only
+	DutyParty string `json:"duty_party"`
+	// CustomsIds Audit the customs in the list only
+	CustomsIds []string `json:"customs_ids"`
+
+	// FileName The audit file name derived from the filter
+	FileName string `json:"file_name"`
+	// FilePath The full path of the audit file
+	FilePath string `json:"file_path"`
+	// OssKey The object key of the uploaded audit file, empty if not uploaded
+	OssKey string `json:"oss_key"`
 
 	AuditData []CustomsAuditObject
 
+	// MissingScreenshots The screenshots failed to fetch from OSS
+	MissingScreenshots []MissingScreenshot
+	// Findings The suspicious article rows found by the audit rules
+	Findings []Finding
+
 	Errors []string
 }
 
 // queryCustomsAuditData  Query Customs Audit Data
 func (ca *CustomsAudit) queryCustomsAuditData() {
-	fmt.Println(ca.Month)
+	query, args, err := ca.filterQuery()
+	if err != nil {
+		ca.Errors = append(ca.Errors, fmt.Sprintf("Build customs query of filter:%s, error:%v", ca.filterName(), err))
+		return
+	}
 	var customsIds []string
-	err := global.Db.Select(&customsIds, QueryCustomsSubmittedBetweenDate, ca.Month)
+	err = global.Select(global.ReadDb, &customsIds, global.ReadDb.Rebind(query), args...)
 	if err != nil {
-		log.Panic(err)
-		ca.Errors = append(ca.Errors, fmt.Sprintf("Query customs list within month:%s, error:%v", ca.Month, err))
+		ca.Errors = append(ca.Errors, fmt.Sprintf("Query customs list of filter:%s, error:%v", ca.filterName(), err))
+		return
 	}
 
-	log.Infof("The customs total: %d within month: %s", len(customsIds), ca.Month)
+	log.Infof("The customs total: %d of filter: %s", len(customsIds), ca.filterName())
 
-	for idx, id := range customsIds {
-		log.Infof("%d customs id: %s", idx, id)
-		var audit CustomsAuditObject
-		err := global.Db.Get(&audit, QueryCustomsAuditData, id)
+	// 按批次查询报关单的所有商品行
+	viper.SetDefault("audit.batch-size", 200)
+	batchSize := viper.GetInt("audit.batch-size")
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	rows := map[string][]CustomsAuditObject{}
+	for start := 0; start < len(customsIds); start += batchSize {
+		end := start + batchSize
+		if end > len(customsIds) {
+			end = len(customsIds)
+		}
+		batch := customsIds[start:end]
+		log.Infof("Query audit data of customs %d-%d", start+1, end)
 
+		query, args, err := sqlx.In(QueryCustomsAuditDataIn, batch)
 		if err != nil {
-			ca.Errors = append(ca.Errors, fmt.Sprintf("Query customs :%s audit info failed, error:%v", id, err))
-		} else {
-			ca.AuditData = append(ca.AuditData, audit)
+			ca.Errors = append(ca.Errors, fmt.Sprintf("Build audit data query of customs %v, error:%v", batch, err))
+			continue
+		}
+		var data []CustomsAuditObject
+		if err = global.Select(global.ReadDb, &data, global.ReadDb.Rebind(query), args...); err != nil {
+			ca.Errors = append(ca.Errors, fmt.Sprintf("Query customs :%v audit info failed, error:%v", batch, err))
+			continue
+		}
+		for _, d := range data {
+			rows[d.CustomsId] = append(rows[d.CustomsId], d)
+		}
+	}
+
+	// 保持报关单的查询顺序
+	for _, id := range customsIds {
+		if len(rows[id]) == 0 {
+			log.Warnf("The customs: %s has no article", id)
+			continue
+		}
+		ca.AuditData = append(ca.AuditData, rows[id]...)
+	}
+}
+
+// groupByCustoms The consecutive audit rows of the same customs
+func groupByCustoms(data []CustomsAuditObject) [][]CustomsAuditObject {
+	var groups [][]CustomsAuditObject
+	for i, d := range data {
+		if i == 0 || data[i-1].CustomsId != d.CustomsId {
+			groups = append(groups, nil)
 		}
+		groups[len(groups)-1] = append(groups[len(groups)-1], d)
 	}
+	return groups
 }
 
 func (ca *CustomsAudit) fileAuditExcel(fp string) error {
@@ -54,7 +118,8 @@ func (ca *CustomsAudit) fileAuditExcel(fp string) error {
 	file := excelize.NewFile()
 	header := &[]interface{}{
 		"Bill NO.", "Invoice No.", "Invoice Date", "Itemnr", "Statistical Number",
-		"Duty(%)", "Product No.", "Link", "Description", "MRN", "Screenshot",
+		"Duty(%)", "Product No.", "Link", "Description", "MRN", "Declare Country", "Declared Value",
+		"Quantity", "Net Weight(KG)", "Screenshot", "Severity",
 	}
 
 	err := file.SetSheetRow(sname, "A1", header)
@@ -63,48 +128,113 @@ func (ca *CustomsAudit) fileAuditExcel(fp string) error {
 		return err
 	}
 
-	ossClient := oss.Client{
-		Endpoint:        viper.GetString("oss.endpoint"),
-		AccessKeyId:     viper.GetString("oss.access-key"),
-		AccessKeySecret: viper.GetString("oss.access-secret"),
-		BucketName:      viper.GetString("oss.bucket"),
-	}
-
-	tmpDir := viper.GetString("audit.tmp-dir")
-
-	for i, datum := range ca.AuditData {
-		idx := i + 2
-		err = file.SetCellStr(sname, fmt.Sprintf("A%d", idx), datum.BillNo.String)
-		err = file.SetCellStr(sname, fmt.Sprintf("B%d", idx), datum.CustomsId)
-		err = file.SetCellStr(sname, fmt.Sprintf("C%d", idx), datum.InvoiceDate.String)
-		err = file.SetCellStr(sname, fmt.Sprintf("D%d", idx), datum.ItemNumber)
-		err = file.SetCellStr(sname, fmt.Sprintf("E%d", idx), datum.HsCode.String)
-		err = file.SetCellStr(sname, fmt.Sprintf("F%d", idx), datum.EuDutyRate)
-		err = file.SetCellStr(sname, fmt.Sprintf("G%d", idx), datum.ProductNo)
-		err = file.SetCellStr(sname, fmt.Sprintf("H%d", idx), datum.WebLink.String)
-		err = file.SetCellStr(sname, fmt.Sprintf("I%d", idx), datum.Description)
-		err = file.SetCellStr(sname, fmt.Sprintf("J%d", idx), datum.Mrn.String)
-
-		screenshotName := datum.PriceScreenshot.String
-		if screenshotName != "" && !strings.Contains(screenshotName, "http") {
-			tmpPath := filepath.Join(tmpDir, screenshotName)
-			ossErr := ossClient.DownloadOssFile(screenshotName, tmpPath)
-			if ossErr == nil {
-				absPaht, _ := filepath.Abs(tmpPath)
-				fmt.Println("abs path:", absPaht)
-				ossErr = file.SetRowHeight(sname, idx, 200)
-				ossErr = file.AddPicture(sname, fmt.Sprintf("K%d", idx), tmpPath, `{"autofit": true}`)
-				if ossErr != nil {
-					fmt.Println(err)
+	profile := workbook.LoadProfile()
+	screenshots := ca.prefetchScreenshots()
+	opts := loadScreenshotOptions()
+	ca.runRules(screenshots)
+	severities := rowSeverities(ca.Findings)
+	severityStyles, err := newSeverityStyles(file)
+	if err != nil {
+		return err
+	}
+
+	mergedStyle, err := file.NewStyle(&excelize.Style{Alignment: &excelize.Alignment{Vertical: "top"}})
+	if err != nil {
+		return err
+	}
+
+	idx := 1
+	var subtotalRows []int
+	for _, group := range groupByCustoms(ca.AuditData) {
+		first := idx + 1
+		for _, datum := range group {
+			idx++
+			err = file.SetCellStr(sname, fmt.Sprintf("A%d", idx), datum.BillNo.String)
+			err = file.SetCellStr(sname, fmt.Sprintf("B%d", idx), datum.InvoiceNo.String)
+			err = file.SetCellStr(sname, fmt.Sprintf("C%d", idx), datum.InvoiceDate.String)
+			err = file.SetCellStr(sname, fmt.Sprintf("D%d", idx), datum.ItemNumber)
+			err = file.SetCellStr(sname, fmt.Sprintf("E%d", idx), datum.HsCode.String)
+			err = file.SetCellStr(sname, fmt.Sprintf("F%d", idx), datum.EuDutyRate)
+			err = file.SetCellStr(sname, fmt.Sprintf("G%d", idx), datum.ProductNo)
+			err = profile.SetLink(file, sname, fmt.Sprintf("H%d", idx), datum.WebLink.String)
+			err = file.SetCellStr(sname, fmt.Sprintf("I%d", idx), datum.Description)
+			err = file.SetCellStr(sname, fmt.Sprintf("J%d", idx), datum.Mrn.String)
+			err = file.SetCellStr(sname, fmt.Sprintf("K%d", idx), datum.DeclareCountry.String)
+			err = setCellNullFloat(file, sname, fmt.Sprintf("L%d", idx), datum.DeclaredValue)
+			err = setCellNullFloat(file, sname, fmt.Sprintf("M%d", idx), sql.NullFloat64{Float64: float64(datum.Quantity.Int64), Valid: datum.Quantity.Valid})
+			err = setCellNullFloat(file, sname, fmt.Sprintf("N%d", idx), datum.NetWeight)
+
+			screenshotName := datum.PriceScreenshot.String
+			if isOssScreenshot(screenshotName) {
+				screenshotPath, ok := screenshots[screenshotName]
+				if !ok {
+					err = file.SetCellStr(sname, fmt.Sprintf("O%d", idx), "MISSING: "+screenshotName)
+				} else if picErr := ca.addScreenshot(file, sname, idx, screenshotName, screenshotPath, opts, profile); picErr != nil {
+					fmt.Println(picErr)
+					err = file.SetCellStr(sname, fmt.Sprintf("O%d", idx), "INVALID: "+screenshotName)
 				}
 			}
+			if severity := severities[datum.CustomsId+"/"+datum.ItemNumber]; severity != "" {
+				err = file.SetCellStr(sname, fmt.Sprintf("P%d", idx), severity)
+				err = file.SetCellStyle(sname, fmt.Sprintf("P%d", idx), fmt.Sprintf("P%d", idx), severityStyles[severity])
+			}
+
+			if err != nil {
+				return err
+			}
 		}
 
+		// 同一报关单的 Bill NO. 和 MRN 合并单元格
+		if idx > first {
+			for _, col := range []string{"A", "J"} {
+				if err = file.MergeCell(sname, fmt.Sprintf("%s%d", col, first), fmt.Sprintf("%s%d", col, idx)); err != nil {
+					return err
+				}
+				if err = file.SetCellStyle(sname, fmt.Sprintf("%s%d", col, first), fmt.Sprintf("%s%d", col, idx), mergedStyle); err != nil {
+					return err
+				}
+			}
+		}
+
+		// 报关单小计行
+		idx++
+		err = file.SetCellStr(sname, fmt.Sprintf("A%d", idx), "Subtotal")
+		err = file.SetCellStr(sname, fmt.Sprintf("B%d", idx), group[0].InvoiceNo.String)
+		err = file.SetCellStr(sname, fmt.Sprintf("C%d", idx), "Items")
+		err = file.SetCellInt(sname, fmt.Sprintf("D%d", idx), len(group))
+		err = file.SetCellFormula(sname, fmt.Sprintf("L%d", idx), fmt.Sprintf("SUM(L%d:L%d)", first, idx-1))
+		err = file.SetCellFormula(sname, fmt.Sprintf("M%d", idx), fmt.Sprintf("SUM(M%d:M%d)", first, idx-1))
+		err = file.SetCellFormula(sname, fmt.Sprintf("N%d", idx), fmt.Sprintf("SUM(N%d:N%d)", first, idx-1))
+		subtotalRows = append(subtotalRows, idx)
 		if err != nil {
 			return err
 		}
 	}
 
+	// L: 申报金额, M: 数量, N: 净重
+	if err = profile.SetColumnsNumFmt(file, sname, "L", "L", idx, profile.CurrencyNumFmt); err != nil {
+		return err
+	}
+	if err = profile.SetColumnsNumFmt(file, sname, "N", "N", idx, profile.WeightNumFmt); err != nil {
+		return err
+	}
+	if err = setSubtotalStyle(file, sname, subtotalRows, profile); err != nil {
+		return err
+	}
+	if err = profile.ApplySheet(file, sname); err != nil {
+		return err
+	}
+	// 截图列按缩放后的最大宽度
+	if opts.MaxWidth > 0 {
+		if err = file.SetColWidth(sname, "O", "O", float64(opts.MaxWidth)/7+1); err != nil {
+			return err
+		}
+	}
+
+	if err = FillFindingsSheet(file, "Findings", ca.Findings, severityStyles); err != nil {
+		return err
+	}
+
 	if err := file.SaveAs(fp); err != nil {
 		return err
 	}
@@ -112,8 +242,76 @@ func (ca *CustomsAudit) fileAuditExcel(fp string) error {
 	return nil
 }
 
+// setSubtotalStyle Bold and fill the subtotal rows, keep the number formats of the subtotal cells
+func setSubtotalStyle(file *excelize.File, sheetName string, rows []int, profile *workbook.Profile) error {
+	newStyle := func(numFmt string) (int, error) {
+		style := &excelize.Style{
+			Font: &excelize.Font{Bold: true},
+			Fill: excelize.Fill{Type: "pattern", Color: []string{"#F2F2F2"}, Pattern: 1},
+		}
+		if numFmt != "" {
+			style.CustomNumFmt = &numFmt
+		}
+		return file.NewStyle(style)
+	}
+	rowStyle, err := newStyle("")
+	if err != nil {
+		return err
+	}
+	currencyStyle, err := newStyle(profile.CurrencyNumFmt)
+	if err != nil {
+		return err
+	}
+	weightStyle, err := newStyle(profile.WeightNumFmt)
+	if err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if err = file.SetCellStyle(sheetName, fmt.Sprintf("A%d", row), fmt.Sprintf("P%d", row), rowStyle); err != nil {
+			return err
+		}
+		if err = file.SetCellStyle(sheetName, fmt.Sprintf("L%d", row), fmt.Sprintf("L%d", row), currencyStyle); err != nil {
+			return err
+		}
+		if err = file.SetCellStyle(sheetName, fmt.Sprintf("N%d", row), fmt.Sprintf("N%d", row), weightStyle); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// setCellNullFloat Set the number into the cell, the cell is left empty if the number is null
+func setCellNullFloat(file *excelize.File, sheetName, axis string, value sql.NullFloat64) error {
+	if !value.Valid {
+		return nil
+	}
+	return file.SetCellFloat(sheetName, axis, value.Float64, -1, 64)
+}
+
+// addScreenshot Embed the scaled screenshot into the cell and fit the row height to it.
+// The screenshot is linked instead if the scaled file is larger than the threshold.
+func (ca *CustomsAudit) addScreenshot(file *excelize.File, sheetName string, row int, object string, path string, opts screenshotOptions, profile *workbook.Profile) error {
+	scaled, err := scaleScreenshot(path, opts)
+	if err != nil {
+		return err
+	}
+	axis := fmt.Sprintf("O%d", row)
+	if opts.LinkAbove > 0 && scaled.Size > opts.LinkAbove {
+		return profile.SetLink(file, sheetName, axis, opts.LinkPrefix+object)
+	}
+	if err = file.SetRowHeight(sheetName, row, scaled.RowHeight()); err != nil {
+		return err
+	}
+	return file.AddPicture(sheetName, axis, scaled.Path, `{"x_offset": 1, "y_offset": 1, "positioning": "oneCell"}`)
+}
+
 // MakeAudit make audit file
 func (ca *CustomsAudit) MakeAudit() {
+	if err := ca.Validate(); err != nil {
+		ca.Errors = append(ca.Errors, err.Error())
+		return
+	}
 	ca.queryCustomsAuditData()
 	if len(ca.Errors) > 0 {
 		log.Errorf("Query audit data failed, err: %v", ca.Errors)
@@ -124,10 +322,17 @@ func (ca *CustomsAudit) MakeAudit() {
 	if !utils.IsExists(auditSavePath) {
 		utils.CreateDir(auditSavePath)
 	}
-	auditFilename := ca.Month + ".xlsx"
+	// 文件名与 service_audit.created_at 使用同一个 UTC 时间
+	createdAt := time.Now().UTC()
+	ca.FileName = ca.versionedFileName(createdAt)
+	ca.FilePath = filepath.Join(auditSavePath, ca.FileName)
 
-	err := ca.fileAuditExcel(filepath.Join(auditSavePath, auditFilename))
+	err := ca.fileAuditExcel(ca.FilePath)
 	if err != nil {
 		log.Error("Generate audit file failed, err: ", err)
+		ca.Errors = append(ca.Errors, fmt.Sprintf("Generate audit file failed, err: %v", err))
+		return
 	}
+	ca.uploadAuditFile()
+	ca.saveAuditIntoDB(createdAt)
 }
@@ -0,0 +1,103 @@
+package audit
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"sort"
+	"strings"
+	"time"
+)
+
+// DateLayout The layout of the from and to dates of the audit filter
+const DateLayout = "2006-01-02"
+
+// Validate Check the audit filter, at least one of the month, date range, duty party and customs IDs is required
+func (ca *CustomsAudit) Validate() error {
+	if ca.Month == "" && ca.From == "" && ca.To == "" && ca.DutyParty == "" && len(ca.CustomsIds) == 0 {
+		return fmt.Errorf("at least one of month, from/to, duty party and customs IDs is required")
+	}
+	if ca.Month != "" {
+		if _, err := time.Parse("2006-01", ca.Month); err != nil {
+			return fmt.Errorf("the month:%s invalid format(correct: 2006-01)", ca.Month)
+		}
+		if ca.From != "" || ca.To != "" {
+			return fmt.Errorf("the month and from/to can not be used together")
+		}
+	}
+	var from, to time.Time
+	var err error
+	if ca.From != "" {
+		if from, err = time.Parse(DateLayout, ca.From); err != nil {
+			return fmt.Errorf("the from date:%s invalid format(correct: %s)", ca.From, DateLayout)
+		}
+	}
+	if ca.To != "" {
+		if to, err = time.Parse(DateLayout, ca.To); err != nil {
+			return fmt.Errorf("the to date:%s invalid format(correct: %s)", ca.To, DateLayout)
+		}
+	}
+	if ca.From != "" && ca.To != "" && to.Before(from) {
+		return fmt.Errorf("the to date:%s is before the from date:%s", ca.To, ca.From)
+	}
+	return nil
+}
+
+// filterQuery The SQL and args querying the submitted customs IDs by the filter
+func (ca *CustomsAudit) filterQuery() (string, []interface{}, error) {
+	query := QueryCustomsSubmittedByFilter
+	var args []interface{}
+	if ca.Month != "" {
+		query += FilterMonthCondition
+		args = append(args, ca.Month)
+	}
+	if ca.From != "" {
+		query += FilterFromCondition
+		args = append(args, ca.From)
+	}
+	if ca.To != "" {
+		query += FilterToCondition
+		args = append(args, ca.To)
+	}
+	if ca.DutyParty != "" {
+		query += FilterDutyPartyCondition
+		args = append(args, ca.DutyParty)
+	}
+	if len(ca.CustomsIds) > 0 {
+		query += FilterCustomsIdsCondition
+		args = append(args, ca.CustomsIds)
+		return sqlx.In(query, args...)
+	}
+	return query, args, nil
+}
+
+// filterName The audit file name (without extension) derived from the filter,
+// exp: 2022-09, BE0796544895_2022-09, 2022-09-01_2022-09-15, customs-3-1a2b3c4d
+func (ca *CustomsAudit) filterName() string {
+	var parts []string
+	if ca.DutyParty != "" {
+		parts = append(parts, ca.DutyParty)
+	}
+	if ca.Month != "" {
+		parts = append(parts, ca.Month)
+	}
+	if ca.From != "" || ca.To != "" {
+		from, to := ca.From, ca.To
+		if from == "" {
+			from = "begin"
+		}
+		if to == "" {
+			to = "now"
+		}
+		parts = append(parts, from+"_"+to)
+	}
+	if len(ca.CustomsIds) > 0 {
+		// 报关单列表用哈希区分，避免文件名过长
+		ids := append([]string{}, ca.CustomsIds...)
+		sort.Strings(ids)
+		sum := sha256.Sum256([]byte(strings.Join(ids, ",")))
+		parts = append(parts, fmt.Sprintf("customs-%d-%s", len(ids), hex.EncodeToString(sum[:])[:8]))
+	}
+	return strings.Join(parts, "_")
+}
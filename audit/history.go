@@ -0,0 +1,91 @@
+package audit
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/spf13/viper"
+	"path/filepath"
+	"strings"
+	"sysafari.com/customs/tguard/global"
+	"sysafari.com/customs/tguard/oss"
+	"time"
+)
+
+// FileTimeLayout The version of the audit file name in UTC, the same as service_audit.created_at, exp: BE0796544895_2022-09_20220930150405.xlsx
+const FileTimeLayout = "20060102150405"
+
+// versionedFileName The audit file name of the filter with the run time in UTC, so the runs do not overwrite each other
+func (ca *CustomsAudit) versionedFileName(t time.Time) string {
+	return fmt.Sprintf("%s_%s.xlsx", ca.filterName(), t.UTC().Format(FileTimeLayout))
+}
+
+// AuditFilePath The full path of the audit file in audit.save-dir
+func AuditFilePath(fileName string) (string, error) {
+	if fileName == "" || filepath.Ext(fileName) != ".xlsx" || strings.ContainsAny(fileName, `/\`) || strings.HasPrefix(fileName, ".") {
+		return "", fmt.Errorf("The audit filename:%s invalid format(correct: 2022-09_20220930150405.xlsx)", fileName)
+	}
+	return filepath.Join(viper.GetString("audit.save-dir"), fileName), nil
+}
+
+// saveAuditIntoDB Record the audit run into service_audit, the audit file is still usable if it failed
+func (ca *CustomsAudit) saveAuditIntoDB(createdAt time.Time) {
+	filter, _ := json.Marshal(map[string]interface{}{
+		"month":       ca.Month,
+		"from":        ca.From,
+		"to":          ca.To,
+		"duty_party":  ca.DutyParty,
+		"customs_ids": ca.CustomsIds,
+	})
+	customs := map[string]bool{}
+	for _, d := range ca.AuditData {
+		customs[d.CustomsId] = true
+	}
+
+	record := &ServiceAudit{
+		Name:               ca.FileName,
+		Month:              ca.Month,
+		Filter:             string(filter),
+		CustomsTotal:       len(customs),
+		RowTotal:           len(ca.AuditData),
+		MissingScreenshots: len(ca.MissingScreenshots),
+		FindingTotal:       len(ca.Findings),
+		OssKey:             ca.OssKey,
+		CreatedAt:          createdAt.UTC().Format("2006-01-02 15:04:05"),
+	}
+	if _, err := global.Db.NamedExec(InsertServiceAudit, record); err != nil {
+		log.Errorf("Save audit %s into DB failed: %v", ca.FileName, err)
+	}
+}
+
+// uploadAuditFile Upload the audit file if oss.upload is on, the key is by the duty party and month of the filter
+func (ca *CustomsAudit) uploadAuditFile() {
+	month := time.Now()
+	for _, m := range []string{ca.Month, ca.From} {
+		if len(m) >= 7 {
+			if d, err := time.Parse("2006-01", m[:7]); err == nil {
+				month = d
+				break
+			}
+		}
+	}
+	key, err := oss.UploadArtifact(ca.FilePath, ca.DutyParty, month)
+	if err != nil {
+		log.Errorf("Upload audit %s failed: %v", ca.FileName, err)
+		return
+	}
+	if key != "" {
+		log.Infof("Uploaded audit %s as %s", ca.FileName, key)
+	}
+	ca.OssKey = key
+}
+
+// QueryAuditFiles Query the latest audit runs, the month is optional
+func QueryAuditFiles(month string, limit int) ([]ServiceAudit, error) {
+	if limit <= 0 {
+		limit = 50
+	}
+	var audits []ServiceAudit
+	err := global.Db.Select(&audits, QueryServiceAuditList, month, month, limit)
+	return audits, err
+}
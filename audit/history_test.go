@@ -0,0 +1,15 @@
+package audit
+
+import (
+	"testing"
+	"time"
+)
+
+func TestVersionedFileNameIsUTC(t *testing.T) {
+	ca := &CustomsAudit{DutyParty: "BE0796544895", Month: "2022-09"}
+	// 17:04:05 +02:00 即 UTC 15:04:05，与 created_at 一致
+	runAt := time.Date(2022, 9, 30, 17, 4, 5, 0, time.FixedZone("CEST", 2*60*60))
+	if got, want := ca.versionedFileName(runAt), "BE0796544895_2022-09_20220930150405.xlsx"; got != want {
+		t.Errorf("file name = %s, want %s", got, want)
+	}
+}
@@ -0,0 +1,170 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// MaxRowHeight The max row height of Excel in points
+	MaxRowHeight = 409
+	// pixelsPerPoint The pixels of one point at 96 DPI
+	pixelsPerPoint = 96.0 / 72.0
+)
+
+// ScaledScreenshot The screenshot scaled and re-encoded as JPEG
+type ScaledScreenshot struct {
+	Path   string
+	Width  int
+	Height int
+	Size   int64
+}
+
+// RowHeight The row height in points which fits the screenshot, not higher than MaxRowHeight
+func (s *ScaledScreenshot) RowHeight() float64 {
+	return math.Min(float64(s.Height)/pixelsPerPoint+2, MaxRowHeight)
+}
+
+// screenshotOptions The max width and JPEG quality of the embedded screenshots
+type screenshotOptions struct {
+	MaxWidth int
+	Quality  int
+	// LinkAbove Link the screenshot instead of embedding it if the scaled file is larger than it, 0 always embeds
+	LinkAbove int64
+	// LinkPrefix The prefix of the screenshot link, exp: https://bucket.oss-eu-central-1.aliyuncs.com/
+	LinkPrefix string
+}
+
+// loadScreenshotOptions Load the screenshot options from the audit.screenshot-* settings
+func loadScreenshotOptions() screenshotOptions {
+	viper.SetDefault("audit.screenshot-max-width", 800)
+	viper.SetDefault("audit.screenshot-quality", 75)
+	viper.SetDefault("audit.screenshot-link-above", 0)
+	return screenshotOptions{
+		MaxWidth:   viper.GetInt("audit.screenshot-max-width"),
+		Quality:    viper.GetInt("audit.screenshot-quality"),
+		LinkAbove:  viper.GetInt64("audit.screenshot-link-above"),
+		LinkPrefix: viper.GetString("audit.screenshot-link-prefix"),
+	}
+}
+
+// scaleScreenshot Downscale the screenshot to fit the max width and the max row height, and re-encode it as JPEG.
+// The scaled file is cached next to the source by the options, so it is not scaled again.
+func scaleScreenshot(srcPath string, opts screenshotOptions) (*ScaledScreenshot, error) {
+	dstPath := fmt.Sprintf("%s.w%dq%d.jpg", strings.TrimSuffix(srcPath, ".jpg"), opts.MaxWidth, opts.Quality)
+	if s, err := readScaledScreenshot(dstPath); err == nil {
+		return s, nil
+	}
+
+	f, err := os.Open(srcPath)
+	if err != nil {
+		return nil, err
+	}
+	src, _, err := image.Decode(f)
+	_ = f.Close()
+	if err != nil {
+		return nil, fmt.Errorf("decode screenshot %s failed: %v", srcPath, err)
+	}
+
+	b := src.Bounds()
+	width, height := b.Dx(), b.Dy()
+	// 行高要多留2pt，图片高度按 MaxRowHeight-2 计算
+	maxHeight := (MaxRowHeight - 2) * 96 / 72
+	if opts.MaxWidth > 0 && width > opts.MaxWidth {
+		width, height = opts.MaxWidth, height*opts.MaxWidth/b.Dx()
+	}
+	if height > maxHeight {
+		width, height = width*maxHeight/height, maxHeight
+	}
+	if width < 1 {
+		width = 1
+	}
+	if height < 1 {
+		height = 1
+	}
+
+	dst := src
+	if width != b.Dx() || height != b.Dy() {
+		dst = resizeImage(src, width, height)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(dstPath), filepath.Base(dstPath)+".*.tmp")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	err = jpeg.Encode(tmp, dst, &jpeg.Options{Quality: opts.Quality})
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err = os.Rename(tmp.Name(), dstPath); err != nil {
+		return nil, err
+	}
+	return readScaledScreenshot(dstPath)
+}
+
+// readScaledScreenshot Read the size of the scaled screenshot
+func readScaledScreenshot(path string) (*ScaledScreenshot, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	cfg, err := jpeg.DecodeConfig(f)
+	if err != nil {
+		return nil, err
+	}
+	return &ScaledScreenshot{Path: path, Width: cfg.Width, Height: cfg.Height, Size: info.Size()}, nil
+}
+
+// resizeImage Downscale the image by averaging the source pixels covered by every target pixel
+func resizeImage(src image.Image, width, height int) *image.RGBA {
+	b := src.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	for y := 0; y < height; y++ {
+		y0 := b.Min.Y + y*sh/height
+		y1 := b.Min.Y + (y+1)*sh/height
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < width; x++ {
+			x0 := b.Min.X + x*sw/width
+			x1 := b.Min.X + (x+1)*sw/width
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, bl, a, n uint64
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := src.At(sx, sy).RGBA()
+					r, g, bl, a, n = r+uint64(cr), g+uint64(cg), bl+uint64(cb), a+uint64(ca), n+1
+				}
+			}
+			// JPEG 没有透明通道，透明部分按白色背景合成
+			alpha := a / n
+			white := uint64(0xffff) - alpha
+			dst.SetRGBA(x, y, color.RGBA{
+				R: uint8((r/n + white) >> 8),
+				G: uint8((g/n + white) >> 8),
+				B: uint8((bl/n + white) >> 8),
+				A: 0xff,
+			})
+		}
+	}
+	return dst
+}
@@ -0,0 +1,51 @@
+package audit
+
+import (
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestScaleScreenshotFitsMaxRowHeight(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 300, 3000))
+	for y := 0; y < 3000; y++ {
+		for x := 0; x < 300; x++ {
+			src.Set(x, y, color.RGBA{R: uint8(x), G: uint8(y), B: 0x80, A: 0xff})
+		}
+	}
+	srcPath := filepath.Join(t.TempDir(), "tall.png")
+	f, err := os.Create(srcPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = png.Encode(f, src); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	s, err := scaleScreenshot(srcPath, screenshotOptions{MaxWidth: 800, Quality: 75})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if h := s.RowHeight(); h > MaxRowHeight {
+		t.Errorf("row height = %v, want <= %d", h, MaxRowHeight)
+	}
+	if maxHeight := (MaxRowHeight - 2) * 96 / 72; s.Height > maxHeight {
+		t.Errorf("scaled height = %d, want <= %d", s.Height, maxHeight)
+	}
+	if s.Width != 300*s.Height/3000 {
+		t.Errorf("scaled width = %d, want the aspect ratio kept(%d)", s.Width, 300*s.Height/3000)
+	}
+}
+
+func TestRowHeightIsCapped(t *testing.T) {
+	s := &ScaledScreenshot{Height: 2000}
+	if h := s.RowHeight(); h != MaxRowHeight {
+		t.Errorf("row height = %v, want %d", h, MaxRowHeight)
+	}
+}
@@ -5,6 +5,7 @@ import "database/sql"
 type CustomsAuditObject struct {
 	BillNo          sql.NullString `db:"bill_no"`
 	CustomsId       string         `db:"customs_id"`
+	InvoiceNo       sql.NullString `db:"invoice_no"`
 	InvoiceDate     sql.NullString `db:"invoice_date"`
 	Mrn             sql.NullString `db:"mrn"`
 	ItemNumber      string         `db:"item_number"`
@@ -14,4 +15,41 @@ type CustomsAuditObject struct {
 	WebLink         sql.NullString `db:"web_link"`
 	Description     string         `db:"description"`
 	PriceScreenshot sql.NullString `db:"price_screenshot"`
+	// DutyRate The duty rate in percent
+	DutyRate       sql.NullFloat64 `db:"duty_rate"`
+	DeclaredValue  sql.NullFloat64 `db:"declared_value"`
+	Quantity       sql.NullInt64   `db:"quantity"`
+	NetWeight      sql.NullFloat64 `db:"net_weight"`
+	DeclareCountry sql.NullString  `db:"declare_country"`
+	// SubmittedAt The first time the customs was submitted, exp: 2022-09-30 15:04:05
+	SubmittedAt sql.NullString `db:"submitted_at"`
+}
+
+// ProductDeclareObject The historical declaration of the product, one per customs and item
+type ProductDeclareObject struct {
+	CustomsId  string          `db:"customs_id"`
+	ItemNumber string          `db:"item_number"`
+	ProductNo  string          `db:"product_no"`
+	HsCode     sql.NullString  `db:"hs_code"`
+	DutyRate   sql.NullFloat64 `db:"duty_rate"`
+	// SubmittedAt The first time the customs was submitted, exp: 2022-09-30 15:04:05
+	SubmittedAt string `db:"submitted_at"`
+}
+
+// ServiceAudit The audit run recorded in service_audit
+type ServiceAudit struct {
+	// Name The audit file name
+	Name  string `db:"name" json:"name"`
+	Month string `db:"month" json:"month"`
+	// Filter The audit filter in JSON
+	Filter             string `db:"filter" json:"filter"`
+	CustomsTotal       int    `db:"customs_total" json:"customs_total"`
+	RowTotal           int    `db:"row_total" json:"row_total"`
+	MissingScreenshots int    `db:"missing_screenshots" json:"missing_screenshots"`
+	FindingTotal       int    `db:"finding_total" json:"finding_total"`
+	// OssKey The object key of the uploaded audit file, empty if not uploaded
+	OssKey    string `db:"oss_key" json:"oss_key"`
+	CreatedAt string `db:"created_at" json:"created_at"`
+	// Url The signed URL of the uploaded audit file
+	Url string `db:"-" json:"url,omitempty"`
 }
@@ -0,0 +1,403 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/jmoiron/sqlx"
+	"github.com/labstack/gommon/log"
+	"github.com/spf13/viper"
+	"github.com/xuri/excelize/v2"
+	"math"
+	"sort"
+	"strings"
+	"sysafari.com/customs/tguard/global"
+	"sysafari.com/customs/tguard/workbook"
+	"time"
+)
+
+const (
+	SeverityInfo  = "INFO"
+	SeverityWarn  = "WARN"
+	SeverityError = "ERROR"
+
+	RuleMissingWebLink    = "MISSING_WEB_LINK"
+	RuleMissingScreenshot = "MISSING_SCREENSHOT"
+	RuleDutyRateDeviation = "DUTY_RATE_DEVIATION"
+	RuleHsCodeChanged     = "HS_CODE_CHANGED"
+	RuleZeroDeclaredValue = "ZERO_DECLARED_VALUE"
+
+	// SubmitTimeLayout The layout of the submit time of the customs, the same as DATE_FORMAT '%Y-%m-%d %H:%i:%s'
+	SubmitTimeLayout = "2006-01-02 15:04:05"
+)
+
+// severityRank The order of the severities, the higher is more serious
+var severityRank = map[string]int{SeverityInfo: 1, SeverityWarn: 2, SeverityError: 3}
+
+// Finding A suspicious article row found by an audit rule
+type Finding struct {
+	CustomsId  string `json:"customs_id"`
+	ItemNumber string `json:"item_number"`
+	ProductNo  string `json:"product_no"`
+	Rule       string `json:"rule"`
+	Severity   string `json:"severity"`
+	Message    string `json:"message"`
+}
+
+// RuleConfig The setting of an audit rule in the rules file
+type RuleConfig struct {
+	Name     string `mapstructure:"name"`
+	Disabled bool   `mapstructure:"disabled"`
+	Severity string `mapstructure:"severity"`
+	// Threshold The allowed deviation, exp: the duty rate in percentage points
+	Threshold float64 `mapstructure:"threshold"`
+	// HistoryMonths The months of the historical declarations compared with
+	HistoryMonths int `mapstructure:"history-months"`
+}
+
+// RuleContext The data shared by the rules
+type RuleContext struct {
+	// Screenshots The fetched screenshots by object name
+	Screenshots map[string]string
+	// History The historical declarations by product No.
+	History map[string][]ProductDeclareObject
+}
+
+// RuleFunc Check the article row, returns the message if the row is suspicious
+type RuleFunc func(row CustomsAuditObject, ctx *RuleContext, cfg RuleConfig) (string, bool)
+
+// builtinRules The built-in audit rules by name
+var builtinRules = map[string]RuleFunc{
+	RuleMissingWebLink:    checkMissingWebLink,
+	RuleMissingScreenshot: checkMissingScreenshot,
+	RuleDutyRateDeviation: checkDutyRateDeviation,
+	RuleHsCodeChanged:     checkHsCodeChanged,
+	RuleZeroDeclaredValue: checkZeroDeclaredValue,
+}
+
+// defaultRuleConfigs The settings of the built-in rules if the rules file does not override them
+func defaultRuleConfigs() []RuleConfig {
+	return []RuleConfig{
+		{Name: RuleMissingWebLink, Severity: SeverityWarn},
+		{Name: RuleMissingScreenshot, Severity: SeverityWarn},
+		{Name: RuleDutyRateDeviation, Severity: SeverityError, Threshold: 0.5, HistoryMonths: 12},
+		{Name: RuleHsCodeChanged, Severity: SeverityWarn, HistoryMonths: 12},
+		{Name: RuleZeroDeclaredValue, Severity: SeverityError},
+	}
+}
+
+// LoadRuleConfigs Load the rule settings, the rules in audit.rules-file override the defaults by name, exp:
+//
+//	rules:
+//	  - name: DUTY_RATE_DEVIATION
+//	    severity: ERROR
+//	    threshold: 1
+//	    history-months: 6
+//	  - name: MISSING_WEB_LINK
+//	    disabled: true
+func LoadRuleConfigs() ([]RuleConfig, error) {
+	configs := defaultRuleConfigs()
+	rulesFile := viper.GetString("audit.rules-file")
+	if rulesFile == "" {
+		return configs, nil
+	}
+
+	v := viper.New()
+	v.SetConfigFile(rulesFile)
+	if err := v.ReadInConfig(); err != nil {
+		return nil, fmt.Errorf("read audit rules file %s failed: %v", rulesFile, err)
+	}
+	rules, ok := v.Get("rules").([]interface{})
+	if v.IsSet("rules") && !ok {
+		return nil, fmt.Errorf("parse audit rules file %s failed: rules is not a list", rulesFile)
+	}
+
+	for _, r := range rules {
+		// 每条规则单独读取，未配置的字段保留默认值，配置为0的也生效
+		m, ok := r.(map[string]interface{})
+		if !ok {
+			return nil, fmt.Errorf("parse audit rules file %s failed: rule %v is not a map", rulesFile, r)
+		}
+		rv := viper.New()
+		if err := rv.MergeConfigMap(m); err != nil {
+			return nil, fmt.Errorf("parse audit rules file %s failed: %v", rulesFile, err)
+		}
+		var o RuleConfig
+		if err := rv.Unmarshal(&o); err != nil {
+			return nil, fmt.Errorf("parse audit rules file %s failed: %v", rulesFile, err)
+		}
+		o.Name = strings.ToUpper(o.Name)
+		if _, ok := builtinRules[o.Name]; !ok {
+			return nil, fmt.Errorf("unknown audit rule %s in %s", o.Name, rulesFile)
+		}
+		o.Severity = strings.ToUpper(o.Severity)
+		if o.Severity != "" && severityRank[o.Severity] == 0 {
+			return nil, fmt.Errorf("unknown severity %s of audit rule %s", o.Severity, o.Name)
+		}
+		for i := range configs {
+			if configs[i].Name != o.Name {
+				continue
+			}
+			configs[i].Disabled = o.Disabled
+			if o.Severity != "" {
+				configs[i].Severity = o.Severity
+			}
+			if rv.IsSet("threshold") {
+				configs[i].Threshold = o.Threshold
+			}
+			if rv.IsSet("history-months") {
+				configs[i].HistoryMonths = o.HistoryMonths
+			}
+		}
+	}
+	return configs, nil
+}
+
+// runRules Check every article row with the enabled rules, the findings are saved into the audit
+func (ca *CustomsAudit) runRules(screenshots map[string]string) {
+	configs, err := LoadRuleConfigs()
+	if err != nil {
+		ca.Errors = append(ca.Errors, err.Error())
+		return
+	}
+
+	ctx := &RuleContext{Screenshots: screenshots}
+	historyMonths := 0
+	for _, cfg := range configs {
+		if !cfg.Disabled && cfg.HistoryMonths > historyMonths {
+			historyMonths = cfg.HistoryMonths
+		}
+	}
+	if historyMonths > 0 {
+		ctx.History = ca.queryProductHistory(historyMonths)
+	}
+
+	for _, row := range ca.AuditData {
+		for _, cfg := range configs {
+			if cfg.Disabled {
+				continue
+			}
+			if message, hit := builtinRules[cfg.Name](row, ctx, cfg); hit {
+				ca.Findings = append(ca.Findings, Finding{
+					CustomsId:  row.CustomsId,
+					ItemNumber: row.ItemNumber,
+					ProductNo:  row.ProductNo,
+					Rule:       cfg.Name,
+					Severity:   cfg.Severity,
+					Message:    message,
+				})
+			}
+		}
+	}
+	log.Infof("The audit rules found %d suspicious rows", len(ca.Findings))
+}
+
+// queryProductHistory Query the historical declarations of the products in the audit,
+// from the months before the earliest submitted customs to the latest submitted customs of the audit.
+// The history rules find nothing if the query failed, the audit file is still generated.
+func (ca *CustomsAudit) queryProductHistory(months int) map[string][]ProductDeclareObject {
+	seen := map[string]bool{}
+	var products []string
+	var first, last time.Time
+	for _, row := range ca.AuditData {
+		if row.ProductNo != "" && !seen[row.ProductNo] {
+			seen[row.ProductNo] = true
+			products = append(products, row.ProductNo)
+		}
+		submittedAt, err := time.Parse(SubmitTimeLayout, row.SubmittedAt.String)
+		if err != nil {
+			continue
+		}
+		if first.IsZero() || submittedAt.Before(first) {
+			first = submittedAt
+		}
+		if submittedAt.After(last) {
+			last = submittedAt
+		}
+	}
+
+	history := map[string][]ProductDeclareObject{}
+	if first.IsZero() {
+		return history
+	}
+	from, to := first.AddDate(0, -months, 0).Format(SubmitTimeLayout), last.Format(SubmitTimeLayout)
+	batchSize := viper.GetInt("audit.batch-size")
+	if batchSize <= 0 {
+		batchSize = 200
+	}
+	for start := 0; start < len(products); start += batchSize {
+		end := start + batchSize
+		if end > len(products) {
+			end = len(products)
+		}
+		query, args, err := sqlx.In(QueryProductDeclareHistory, products[start:end], from, to)
+		if err != nil {
+			log.Warnf("Build product history query failed, the history rules are skipped, error:%v", err)
+			continue
+		}
+		var data []ProductDeclareObject
+		if err = global.Select(global.ReadDb, &data, global.ReadDb.Rebind(query), args...); err != nil {
+			log.Warnf("Query product history failed, the history rules are skipped, error:%v", err)
+			continue
+		}
+		for _, d := range data {
+			history[d.ProductNo] = append(history[d.ProductNo], d)
+		}
+	}
+	return history
+}
+
+// otherDeclarations The declarations of the product in other customs submitted within the months before the row's customs,
+// the history is queried by the longest window of all rules
+func (ctx *RuleContext) otherDeclarations(row CustomsAuditObject, months int) []ProductDeclareObject {
+	submittedAt, err := time.Parse(SubmitTimeLayout, row.SubmittedAt.String)
+	if err != nil {
+		return nil
+	}
+	from, to := submittedAt.AddDate(0, -months, 0), submittedAt
+	var others []ProductDeclareObject
+	for _, h := range ctx.History[row.ProductNo] {
+		t, err := time.Parse(SubmitTimeLayout, h.SubmittedAt)
+		if err != nil || h.CustomsId == row.CustomsId {
+			continue
+		}
+		if !t.Before(from) && t.Before(to) {
+			others = append(others, h)
+		}
+	}
+	return others
+}
+
+// checkMissingWebLink The product has no web link
+func checkMissingWebLink(row CustomsAuditObject, _ *RuleContext, _ RuleConfig) (string, bool) {
+	if strings.TrimSpace(row.WebLink.String) == "" {
+		return "The product has no web link", true
+	}
+	return "", false
+}
+
+// checkMissingScreenshot The product has no price screenshot, or it can not be fetched
+func checkMissingScreenshot(row CustomsAuditObject, ctx *RuleContext, _ RuleConfig) (string, bool) {
+	name := row.PriceScreenshot.String
+	if strings.TrimSpace(name) == "" {
+		return "The product has no price screenshot", true
+	}
+	if _, ok := ctx.Screenshots[name]; isOssScreenshot(name) && !ok {
+		return fmt.Sprintf("The price screenshot %s can not be fetched", name), true
+	}
+	return "", false
+}
+
+// checkDutyRateDeviation The duty rate deviates from the median rate of the historical declarations of the product
+func checkDutyRateDeviation(row CustomsAuditObject, ctx *RuleContext, cfg RuleConfig) (string, bool) {
+	if !row.DutyRate.Valid {
+		return "", false
+	}
+	var rates []float64
+	for _, h := range ctx.otherDeclarations(row, cfg.HistoryMonths) {
+		if h.DutyRate.Valid {
+			rates = append(rates, h.DutyRate.Float64)
+		}
+	}
+	if len(rates) == 0 {
+		return "", false
+	}
+	sort.Float64s(rates)
+	median := rates[len(rates)/2]
+	if len(rates)%2 == 0 {
+		median = (rates[len(rates)/2-1] + rates[len(rates)/2]) / 2
+	}
+	if math.Abs(row.DutyRate.Float64-median) > cfg.Threshold {
+		return fmt.Sprintf("The duty rate %.2f%% deviates from the historical rate %.2f%% of %d declarations", row.DutyRate.Float64, median, len(rates)), true
+	}
+	return "", false
+}
+
+// checkHsCodeChanged The HS code differs from the historical declarations of the product
+func checkHsCodeChanged(row CustomsAuditObject, ctx *RuleContext, cfg RuleConfig) (string, bool) {
+	seen := map[string]bool{}
+	var codes []string
+	for _, h := range ctx.otherDeclarations(row, cfg.HistoryMonths) {
+		code := h.HsCode.String
+		if code != "" && code != row.HsCode.String && !seen[code] {
+			seen[code] = true
+			codes = append(codes, code)
+		}
+	}
+	if len(codes) > 0 {
+		sort.Strings(codes)
+		return fmt.Sprintf("The HS code %s was declared as %s before", row.HsCode.String, strings.Join(codes, ", ")), true
+	}
+	return "", false
+}
+
+// checkZeroDeclaredValue The declared value is zero or empty
+func checkZeroDeclaredValue(row CustomsAuditObject, _ *RuleContext, _ RuleConfig) (string, bool) {
+	if !row.DeclaredValue.Valid || row.DeclaredValue.Float64 <= 0 {
+		return "The declared value is zero", true
+	}
+	return "", false
+}
+
+// rowSeverities The highest severity of the findings per article row, keyed by customs ID and item number
+func rowSeverities(findings []Finding) map[string]string {
+	severities := map[string]string{}
+	for _, f := range findings {
+		key := f.CustomsId + "/" + f.ItemNumber
+		if severityRank[f.Severity] > severityRank[severities[key]] {
+			severities[key] = f.Severity
+		}
+	}
+	return severities
+}
+
+// newSeverityStyles The fill styles of the severity cells
+func newSeverityStyles(file *excelize.File) (map[string]int, error) {
+	colors := map[string]string{SeverityInfo: "#DDEBF7", SeverityWarn: "#FFEB9C", SeverityError: "#FFC7CE"}
+	styles := map[string]int{}
+	for severity, color := range colors {
+		style, err := file.NewStyle(&excelize.Style{
+			Fill: excelize.Fill{Type: "pattern", Color: []string{color}, Pattern: 1},
+		})
+		if err != nil {
+			return nil, err
+		}
+		styles[severity] = style
+	}
+	return styles, nil
+}
+
+// FillFindingsSheet fill the findings of the audit rules, the most serious first
+func FillFindingsSheet(file *excelize.File, sheetName string, findings []Finding, severityStyles map[string]int) error {
+	log.Info("Findings sheet name: ", sheetName)
+	profile := workbook.LoadProfile()
+	file.NewSheet(sheetName)
+
+	headers := &[]interface{}{"SN", "Severity", "Rule", "Customs ID", "Itemnr", "Product No.", "Message"}
+	err := file.SetSheetRow(sheetName, "A1", headers)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	sorted := append([]Finding{}, findings...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		return severityRank[sorted[i].Severity] > severityRank[sorted[j].Severity]
+	})
+	for i, datum := range sorted {
+		sn := i + 1
+		idx := sn + 1
+		err = file.SetCellInt(sheetName, fmt.Sprintf("A%d", idx), sn)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("B%d", idx), datum.Severity)
+		err = file.SetCellStyle(sheetName, fmt.Sprintf("B%d", idx), fmt.Sprintf("B%d", idx), severityStyles[datum.Severity])
+		err = file.SetCellStr(sheetName, fmt.Sprintf("C%d", idx), datum.Rule)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("D%d", idx), datum.CustomsId)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("E%d", idx), datum.ItemNumber)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("F%d", idx), datum.ProductNo)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("G%d", idx), datum.Message)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return profile.ApplySheet(file, sheetName)
+}
@@ -0,0 +1,74 @@
+package audit
+
+import (
+	"database/sql"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadRuleConfigsAllowsZero(t *testing.T) {
+	rulesFile := filepath.Join(t.TempDir(), "rules.yaml")
+	content := `rules:
+  - name: DUTY_RATE_DEVIATION
+    threshold: 0
+  - name: hs_code_changed
+    history-months: 3
+`
+	if err := os.WriteFile(rulesFile, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("audit.rules-file", rulesFile)
+	defer viper.Set("audit.rules-file", "")
+
+	configs, err := LoadRuleConfigs()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, cfg := range configs {
+		switch cfg.Name {
+		case RuleDutyRateDeviation:
+			if cfg.Threshold != 0 || cfg.HistoryMonths != 12 {
+				t.Errorf("%s threshold/history = %v/%d, want 0/12", cfg.Name, cfg.Threshold, cfg.HistoryMonths)
+			}
+		case RuleHsCodeChanged:
+			if cfg.HistoryMonths != 3 || cfg.Severity != SeverityWarn {
+				t.Errorf("%s history/severity = %d/%s, want 3/%s", cfg.Name, cfg.HistoryMonths, cfg.Severity, SeverityWarn)
+			}
+		}
+	}
+}
+
+func TestHistoryRulesUseTheirOwnWindow(t *testing.T) {
+	row := CustomsAuditObject{
+		CustomsId:   "C1",
+		ProductNo:   "P1",
+		HsCode:      sql.NullString{String: "6109100010", Valid: true},
+		SubmittedAt: sql.NullString{String: "2022-09-15 10:00:00", Valid: true},
+	}
+	ctx := &RuleContext{History: map[string][]ProductDeclareObject{
+		"P1": {
+			{CustomsId: "C1", ProductNo: "P1", HsCode: sql.NullString{String: "6110209100", Valid: true}, SubmittedAt: "2022-09-15 10:00:00"},
+			{CustomsId: "C2", ProductNo: "P1", HsCode: sql.NullString{String: "6109100010", Valid: true}, SubmittedAt: "2022-08-01 08:00:00"},
+			{CustomsId: "C3", ProductNo: "P1", HsCode: sql.NullString{String: "6110209100", Valid: true}, SubmittedAt: "2022-01-20 08:00:00"},
+			// 审核报关单之后的申报不算历史
+			{CustomsId: "C4", ProductNo: "P1", HsCode: sql.NullString{String: "6110209100", Valid: true}, SubmittedAt: "2022-10-01 08:00:00"},
+		},
+	}}
+
+	if _, hit := checkHsCodeChanged(row, ctx, RuleConfig{HistoryMonths: 6}); hit {
+		t.Error("only the declarations within 6 months before the customs are compared")
+	}
+	if _, hit := checkHsCodeChanged(row, ctx, RuleConfig{HistoryMonths: 12}); !hit {
+		t.Error("the declaration 8 months before the customs is in the 12 months window")
+	}
+
+	others := ctx.otherDeclarations(row, 12)
+	if len(others) != 2 || others[0].CustomsId != "C2" || others[1].CustomsId != "C3" {
+		t.Errorf("other declarations = %v, want C2 and C3", others)
+	}
+	if others := ctx.otherDeclarations(CustomsAuditObject{CustomsId: "C1", ProductNo: "P1"}, 12); len(others) != 0 {
+		t.Errorf("other declarations of a customs without submit time = %v, want none", others)
+	}
+}
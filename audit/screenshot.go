@@ -0,0 +1,156 @@
+package audit
+
+import (
+	"fmt"
+	"github.com/labstack/gommon/log"
+	"github.com/spf13/viper"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sysafari.com/customs/tguard/oss"
+	"sysafari.com/customs/tguard/utils"
+)
+
+// MissingScreenshot The screenshot which can not be fetched from OSS
+type MissingScreenshot struct {
+	CustomsId string `json:"customs_id"`
+	ProductNo string `json:"product_no"`
+	Object    string `json:"object"`
+	Error     string `json:"error"`
+}
+
+// screenshotJob The screenshot object to fetch
+type screenshotJob struct {
+	Object string
+	// Path The cached local file, empty if failed
+	Path  string
+	Error string
+}
+
+// isOssScreenshot Whether the screenshot is an OSS object, the HTTP links are written as links
+func isOssScreenshot(name string) bool {
+	return name != "" && !strings.Contains(name, "http")
+}
+
+// screenshotCachePath The cache file of the object, keyed by the object name and ETag,
+// so the same object is not downloaded again across runs and a changed object is downloaded again
+func screenshotCachePath(cacheDir string, object string, etag string) string {
+	dir, name := filepath.Split(strings.TrimPrefix(object, "/"))
+	if etag != "" {
+		name = etag + "-" + name
+	}
+	return filepath.Join(cacheDir, dir, name)
+}
+
+// fetchScreenshot Fetch the screenshot into the cache directory if it is not cached
+func fetchScreenshot(store oss.ObjectStore, cacheDir string, object string) (string, error) {
+	info, err := store.Stat(object)
+	if err != nil {
+		return "", err
+	}
+	cachePath := screenshotCachePath(cacheDir, object, info.ETag)
+	if utils.IsExists(cachePath) {
+		return cachePath, nil
+	}
+
+	dir := filepath.Dir(cachePath)
+	if !utils.IsDir(dir) && !utils.CreateDir(dir) {
+		return "", fmt.Errorf("create screenshot cache dir: %s failed", dir)
+	}
+	// 先下载到临时文件，避免中断后留下不完整的缓存
+	tmp, err := os.CreateTemp(dir, filepath.Base(cachePath)+".*.tmp")
+	if err != nil {
+		return "", err
+	}
+	_ = tmp.Close()
+	defer os.Remove(tmp.Name())
+
+	if err = store.GetToFile(object, tmp.Name()); err != nil {
+		return "", err
+	}
+	if err = os.Rename(tmp.Name(), cachePath); err != nil {
+		return "", err
+	}
+	return cachePath, nil
+}
+
+// prefetchScreenshots Fetch the screenshots of the audit data with a bounded worker pool and a shared object store.
+// The same object is fetched only once, returns the local path of every fetched object.
+func (ca *CustomsAudit) prefetchScreenshots() map[string]string {
+	viper.SetDefault("audit.screenshot-workers", 4)
+	cacheDir := viper.GetString("audit.tmp-dir")
+
+	var jobs []*screenshotJob
+	seen := map[string]bool{}
+	for _, datum := range ca.AuditData {
+		object := datum.PriceScreenshot.String
+		if !isOssScreenshot(object) || seen[object] {
+			continue
+		}
+		seen[object] = true
+		jobs = append(jobs, &screenshotJob{Object: object})
+	}
+	log.Infof("Prefetching %d screenshots into %s", len(jobs), cacheDir)
+
+	store, storeErr := oss.NewStore()
+	if storeErr != nil {
+		log.Errorf("Create object store failed: %v", storeErr)
+	}
+	workers := viper.GetInt("audit.screenshot-workers")
+	if workers <= 0 {
+		workers = 1
+	}
+	ch := make(chan *screenshotJob)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range ch {
+				if storeErr != nil {
+					job.Error = storeErr.Error()
+					continue
+				}
+				path, err := fetchScreenshot(store, cacheDir, job.Object)
+				if err != nil {
+					job.Error = err.Error()
+					continue
+				}
+				job.Path = path
+			}
+		}()
+	}
+	for _, job := range jobs {
+		ch <- job
+	}
+	close(ch)
+	wg.Wait()
+
+	paths := map[string]string{}
+	failed := map[string]string{}
+	for _, job := range jobs {
+		if job.Error != "" {
+			failed[job.Object] = job.Error
+			continue
+		}
+		paths[job.Object] = job.Path
+	}
+
+	// 缺失的截图按报关单和产品列出
+	for _, datum := range ca.AuditData {
+		object := datum.PriceScreenshot.String
+		if e, ok := failed[object]; ok {
+			ca.MissingScreenshots = append(ca.MissingScreenshots, MissingScreenshot{
+				CustomsId: datum.CustomsId,
+				ProductNo: datum.ProductNo,
+				Object:    object,
+				Error:     e,
+			})
+		}
+	}
+	if len(ca.MissingScreenshots) > 0 {
+		log.Warnf("%d screenshots of %d objects are missing: %v", len(ca.MissingScreenshots), len(failed), ca.MissingScreenshots)
+	}
+	return paths
+}
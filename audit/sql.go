@@ -1,15 +1,35 @@
 package audit
 
 const (
-	// QueryCustomsSubmittedBetweenDate Query the customs IDs submitted within the month
-	QueryCustomsSubmittedBetweenDate = `SELECT DISTINCT customs_id
-FROM log_customs_state
-WHERE state = 'SUBMITTED'
-  AND DATE_FORMAT(gmt_create, '%Y-%m') = ?;`
+	// QueryCustomsSubmittedByFilter Query the submitted customs IDs, the filter conditions are appended by the audit filter
+	QueryCustomsSubmittedByFilter = `SELECT DISTINCT lcs.customs_id
+FROM log_customs_state lcs
+         INNER JOIN base_customs bc ON lcs.customs_id = bc.customs_id
+WHERE lcs.state = 'SUBMITTED'`
 
-	QueryCustomsAuditData = `SELECT bb.bill_no,
+	// FilterMonthCondition Submitted within the month, exp: 2006-01
+	FilterMonthCondition = ` AND DATE_FORMAT(lcs.gmt_create, '%Y-%m') = ?`
+	// FilterFromCondition Submitted on or after the date, exp: 2006-01-02
+	FilterFromCondition = ` AND lcs.gmt_create >= ?`
+	// FilterToCondition Submitted before the day after the date, exp: 2006-01-02
+	FilterToCondition = ` AND lcs.gmt_create < DATE_ADD(?, INTERVAL 1 DAY)`
+	// FilterDutyPartyCondition Customs of the duty party
+	FilterDutyPartyCondition = ` AND bc.duty_party = ?`
+	// FilterCustomsIdsCondition Customs in the list, expanded by sqlx.In
+	FilterCustomsIdsCondition = ` AND lcs.customs_id IN (?)`
+
+	// queryCustomsAuditDataSelect The article rows of the customs audit, the conditions are appended
+	queryCustomsAuditDataSelect = `SELECT bb.bill_no,
        sca.customs_id,
+       bc.invoice_no,
+       (SELECT DATE_FORMAT(lcp.gmt_create, '%Y/%m/%d')
+        FROM log_clearance_process lcp
+        WHERE lcp.customs_id = sca.customs_id
+          AND (lcp.process_code = 'TAX' OR lcp.process_code = 'TMP_TAX')
+        ORDER BY lcp.process_code = 'TAX' DESC, lcp.gmt_create DESC
+        LIMIT 1)                                                             AS invoice_date,
        bc.mrn,
+       bc.declare_country,
        sca.item_number,
        IFNULL(scvp.hs_code, sca.hs_code)                                     AS hs_code,
        CONCAT(FORMAT(IFNULL(scvp.eu_duty_rate, sca.duty_amount / sca.final_declared_value) * 100, 2),
@@ -17,12 +37,51 @@ WHERE state = 'SUBMITTED'
        sca.product_no,
        bd.web_link,
        bd.description,
-       scvp.price_screenshot
+       scvp.price_screenshot,
+       IFNULL(scvp.eu_duty_rate, sca.duty_amount / sca.final_declared_value) * 100 AS duty_rate,
+       sca.final_declared_value                                              AS declared_value,
+       sca.quantity,
+       sca.net_weight,
+       (SELECT DATE_FORMAT(MIN(lcs.gmt_create), '%Y-%m-%d %H:%i:%s')
+        FROM log_customs_state lcs
+        WHERE lcs.customs_id = sca.customs_id
+          AND lcs.state = 'SUBMITTED')                                       AS submitted_at
 FROM service_customs_article sca
          INNER JOIN base_description bd ON sca.product_no = bd.product_no AND sca.country = bd.country
          INNER JOIN base_customs bc ON sca.customs_id = bc.customs_id
-         INNER JOIN service_bill_customs sbc ON sca.customs_id = sbc.customs_id
+         INNER JOIN service_bill_customs sbc ON sbc.is_removed = 0 AND sca.customs_id = sbc.customs_id
          INNER JOIN base_bill bb ON sbc.bill_id = bb.bill_id
          LEFT JOIN service_customs_value_process scvp ON sca.customs_value_process_id = scvp.id
-WHERE sca.customs_id =?`
+`
+
+	// QueryCustomsAuditDataIn Query the article rows of the customs list, expanded by sqlx.In
+	QueryCustomsAuditDataIn = queryCustomsAuditDataSelect + `WHERE sca.customs_id IN (?)
+ORDER BY sca.customs_id, CAST(sca.item_number AS UNSIGNED), sca.item_number`
+
+	// QueryProductDeclareHistory Query the declarations of the products first submitted within [from, to), expanded by sqlx.In.
+	// A customs may be submitted more than once, every customs item is one row with its first submit time.
+	QueryProductDeclareHistory = `SELECT sca.customs_id,
+       sca.item_number,
+       sca.product_no,
+       IFNULL(scvp.hs_code, sca.hs_code)                                     AS hs_code,
+       IFNULL(scvp.eu_duty_rate, sca.duty_amount / sca.final_declared_value) * 100 AS duty_rate,
+       DATE_FORMAT(MIN(lcs.gmt_create), '%Y-%m-%d %H:%i:%s')                 AS submitted_at
+FROM service_customs_article sca
+         INNER JOIN log_customs_state lcs ON sca.customs_id = lcs.customs_id AND lcs.state = 'SUBMITTED'
+         LEFT JOIN service_customs_value_process scvp ON sca.customs_value_process_id = scvp.id
+WHERE sca.product_no IN (?)
+GROUP BY sca.customs_id, sca.item_number, sca.product_no, hs_code, duty_rate
+HAVING MIN(lcs.gmt_create) >= ?
+   AND MIN(lcs.gmt_create) < ?`
+
+	// InsertServiceAudit Insert the audit run into service_audit
+	InsertServiceAudit = `INSERT INTO service_audit (name, month, filter, customs_total, row_total, missing_screenshots, finding_total, oss_key, created_at)
+values (:name, :month, :filter, :customs_total, :row_total, :missing_screenshots, :finding_total, :oss_key, :created_at);`
+
+	// QueryServiceAuditList Query the latest audit runs, the month is optional
+	QueryServiceAuditList = `SELECT name, month, filter, customs_total, row_total, missing_screenshots, finding_total, oss_key, created_at
+FROM service_audit
+WHERE (? = '' OR month = ?)
+ORDER BY id DESC
+LIMIT ?;`
 )
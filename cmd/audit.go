@@ -7,23 +7,38 @@ package cmd
 import (
 	"fmt"
 	"github.com/labstack/gommon/log"
+	"os"
+	"strings"
 	"sysafari.com/customs/tguard/audit"
-	"sysafari.com/customs/tguard/global"
 	"time"
 
 	"github.com/spf13/cobra"
 )
 
+var auditFrom string
+var auditTo string
+
 // auditCmd represents the audit command
 var auditCmd = &cobra.Command{
 	Use:   "audit",
 	Short: "生成指定月份的报关自检文件",
-	Long:  `指定月份生成系统已报关的报关自检文件. For example:`,
+	Long: `生成系统已报关的报关自检文件，可按月份、日期范围、税代或报关单筛选. For example:
+
+1. 生成当月的自检文件：				tguard audit
+2. 生成税代2022-09的自检文件：			tguard audit --month 2022-09 --duty-party BE0796544895
+3. 生成日期范围内的自检文件：			tguard audit --from 2022-09-01 --to 2022-09-15
+4. 生成指定报关单的自检文件：			tguard audit --customs-id C1 --customs-id C2
+5. 生成文件中报关单的自检文件（每行一个）：	tguard audit --customs-id @customs.txt`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("audit called")
 
+		// 指定日期范围或报关单且未指定月份时，不按月份筛选
+		if !cmd.Flags().Changed("month") && (auditFrom != "" || auditTo != "" || len(customsIds) > 0) {
+			month = ""
+		}
+
 		// Init database connection
-		global.InitGlobalDatabaseConnection()
+		initDatabase()
 
 		makeAudit()
 	},
@@ -43,22 +58,62 @@ func init() {
 	// auditCmd.Flags().BoolP("toggle", "t", false, "Help message for toggle")
 	auditCmd.Flags().IntVar(&offset, "offset", 0, "指定日期往前偏移的月份数，默认为0（表示不偏移月份，生成指定日期的ICP）")
 	auditCmd.Flags().StringVar(&month, "month", time.Now().Format(MonthFormatLayout), "指定月份，默认(2006-01)")
+	auditCmd.Flags().StringVar(&auditFrom, "from", "", "提交日期起始（含），格式2006-01-02")
+	auditCmd.Flags().StringVar(&auditTo, "to", "", "提交日期截止（含），格式2006-01-02")
+	auditCmd.Flags().StringVar(&dutyParty, "duty-party", "", "税代（duty party）")
+	auditCmd.Flags().StringArrayVar(&customsIds, "customs-id", nil, "指定报关单，可重复指定；@文件名 从文件读取，每行一个")
+}
+
+// readCustomsIds Expand the @file arguments into the customs IDs in the file, one per line
+func readCustomsIds(args []string) ([]string, error) {
+	var ids []string
+	for _, arg := range args {
+		if !strings.HasPrefix(arg, "@") {
+			ids = append(ids, arg)
+			continue
+		}
+		content, err := os.ReadFile(strings.TrimPrefix(arg, "@"))
+		if err != nil {
+			return nil, err
+		}
+		for _, line := range strings.Split(string(content), "\n") {
+			if id := strings.TrimSpace(line); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return ids, nil
 }
 
 func makeAudit() {
-	monthT, err := time.Parse(MonthFormatLayout, month)
+	monthlyStr := ""
+	if month != "" {
+		monthT, err := time.Parse(MonthFormatLayout, month)
+		if err != nil {
+			log.Panic("Date format error", err)
+		}
+		monthlyStr = monthT.AddDate(0, -offset, 0).Format(MonthFormatLayout)
+	}
+	ids, err := readCustomsIds(customsIds)
 	if err != nil {
-		log.Panic("Date format error", err)
+		log.Panic("Read customs IDs failed", err)
 	}
-	monthly := monthT.AddDate(0, -offset, 0)
-	monthlyStr := monthly.Format(MonthFormatLayout)
 
 	start := time.Now().UnixMilli()
-	// make ICP for month
+	// make audit for the filter
 	customsAudit := audit.CustomsAudit{
-		Month: monthlyStr,
+		Month:      monthlyStr,
+		From:       auditFrom,
+		To:         auditTo,
+		DutyParty:  dutyParty,
+		CustomsIds: ids,
 	}
 	customsAudit.MakeAudit()
+	if len(customsAudit.Errors) > 0 {
+		log.Printf("Make audit errors: %v\n", customsAudit.Errors)
+	} else {
+		log.Printf("Make audit success, the file: %s\n", customsAudit.FilePath)
+	}
 
 	end := time.Now().UnixMilli()
 
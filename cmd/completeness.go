@@ -0,0 +1,44 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"log"
+	"sysafari.com/customs/tguard/icp"
+	"time"
+)
+
+// completenessCmd represents the completeness command
+var completenessCmd = &cobra.Command{
+	Use:   "completeness",
+	Short: "检查指定月份报关单的POD和税单是否完整",
+	Long: `列出缺少POD、没有物流信息的运单，以及只有临时税单（TMP_TAX）或没有任何税单记录的报关单，报告保存为xlsx和JSON。
+未指定税代时，检查该月份所有税代。
+For example:
+
+1. 检查当月所有税代：			tguard completeness
+2. 检查2024-09所有税代：		tguard completeness --month 2024-09
+3. 检查税代2024-09：			tguard completeness --month 2024-09 --duty-party BE0796544895`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("completeness called")
+		if _, err := time.Parse(MonthFormatLayout, month); err != nil {
+			log.Panic("Date format error", err)
+		}
+
+		// Init database connection
+		initDatabase()
+
+		report := icp.MakeCompletenessReport(month, dutyParty)
+		if len(report.Errors) > 0 {
+			log.Printf("Check completeness in the month %s errors: %v\n", month, report.Errors)
+		}
+		log.Printf("Checked %d customs in the month %s, %d issues, the report: %s\n", report.CustomsTotal, month, len(report.Issues), report.FileName)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(completenessCmd)
+
+	completenessCmd.Flags().StringVar(&month, "month", time.Now().Format(MonthFormatLayout), "指定月份，默认为命令执行时当前月份(2006-01)")
+	completenessCmd.Flags().StringVar(&dutyParty, "duty-party", "", "税代（duty party），为空时检查所有税代")
+}
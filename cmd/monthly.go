@@ -7,7 +7,6 @@ package cmd
 import (
 	"fmt"
 	"log"
-	"sysafari.com/customs/tguard/global"
 	icp2 "sysafari.com/customs/tguard/icp"
 	"time"
 
@@ -36,7 +35,7 @@ For example:
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("monthly called")
 		// Init database connection
-		global.InitGlobalDatabaseConnection()
+		initDatabase()
 
 		makeICPForOneMonth()
 	},
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"log"
+	"sysafari.com/customs/tguard/icp"
+)
+
+var icpFileName string
+
+// packageCmd represents the package command
+var packageCmd = &cobra.Command{
+	Use:   "package",
+	Short: "将ICP文件与税单、POD、vat-note等文件打包为提交税局的压缩包",
+	Long: `下载ICP中引用的税单、POD文件及vat-note，将ICP中的链接改为包内相对路径，并生成带索引表的压缩包。
+压缩包与ICP文件保存在同一目录。
+For example:
+
+tguard package --icp BE0796544895_202209_01154020.xlsx`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("package called")
+		if icpFileName == "" {
+			log.Panic("The ICP filename is required.")
+		}
+
+		// Init database connection
+		initDatabase()
+
+		pkg := icp.MakeSubmissionPackage(icpFileName)
+		if len(pkg.Errors) > 0 {
+			log.Printf("Make submission package of ICP %s failed, errors: %v\n", icpFileName, pkg.Errors)
+			return
+		}
+		missing := 0
+		for _, d := range pkg.Documents {
+			if d.Error != "" {
+				missing++
+			}
+		}
+		log.Printf("Make submission package %s success, %d documents, %d missing.\n", pkg.FilePath, len(pkg.Documents), missing)
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(packageCmd)
+
+	packageCmd.Flags().StringVar(&icpFileName, "icp", "", "ICP文件名，例如: BE0796544895_202209_01154020.xlsx")
+}
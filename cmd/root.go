@@ -30,13 +30,21 @@ to quickly create a Cobra application.`,
 	// has an action associated with it:
 	Run: func(cmd *cobra.Command, args []string) {
 		// Init database connection
-		global.InitGlobalDatabaseConnection()
+		initDatabase()
 
 		// At last
 		echoRoutes()
 	},
 }
 
+// initDatabase Init the database connection, exit if the database is unavailable
+func initDatabase() {
+	if err := global.InitGlobalDatabaseConnection(); err != nil {
+		fmt.Printf("Init database connection failed: %v\n", err)
+		os.Exit(1)
+	}
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -67,6 +75,7 @@ func echoRoutes() {
 	e.GET("/swagger/*", echoSwagger.WrapHandler)
 
 	e.POST("/icp/append", web.AppendToICP)
+	e.POST("/icp/remove", web.RemoveFromICP)
 
 	// http://domain.example.com/icp/taxAgency/BE0796544895?month=2022-09
 	e.GET("/icp/taxAgency/:dutyParty", web.MakeICPForTaxAgency)
@@ -75,6 +84,28 @@ func echoRoutes() {
 	// http://domain.example.com/icp/download/BE0796544895_202209_01154020.xlsx
 	e.GET("/icp/download/:filename", web.DownloadFile)
 
+	// http://domain.example.com/icp/package/BE0796544895_202209_01154020.xlsx
+	e.POST("/icp/package/:filename", web.MakeSubmissionPackage)
+	// http://domain.example.com/icp/package/download/BE0796544895_202209_01154020-package.zip
+	e.GET("/icp/package/download/:name", web.DownloadSubmissionPackage)
+
+	// http://domain.example.com/vatnote/BE0796544895?month=2022-09
+	e.POST("/vatnote/:dutyParty", web.MakeVatNote)
+	// http://domain.example.com/vatnote/download/2022-09-BE0796544895-vatnote.zip
+	e.GET("/vatnote/download/:name", web.DownloadVatNote)
+
+	// http://domain.example.com/completeness?month=2024-09&dutyParty=BE0796544895
+	e.GET("/completeness", web.MakeCompletenessReport)
+	// http://domain.example.com/completeness/download/2024-09-BE0796544895-completeness.xlsx
+	e.GET("/completeness/download/:name", web.DownloadCompletenessReport)
+
+	// http://domain.example.com/audit
+	e.POST("/audit", web.MakeAudit)
+	// http://domain.example.com/audit/files?month=2022-09
+	e.GET("/audit/files", web.AuditFiles)
+	// http://domain.example.com/audit/download/2022-09_20220930150405.xlsx
+	e.GET("/audit/download/:name", web.DownloadAudit)
+
 	port := viper.GetString("port")
 	if port == "" {
 		port = "1324"
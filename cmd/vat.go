@@ -7,7 +7,6 @@ package cmd
 import (
 	"fmt"
 	"github.com/spf13/cobra"
-	"sysafari.com/customs/tguard/global"
 	"sysafari.com/customs/tguard/icp"
 )
 
@@ -25,7 +24,7 @@ to quickly create a Cobra application.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		fmt.Println("vat called")
 		// Init database connection
-		global.InitGlobalDatabaseConnection()
+		initDatabase()
 
 		icp.MakeICPByVatNo(vatNo)
 	},
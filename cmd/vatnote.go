@@ -0,0 +1,55 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/spf13/cobra"
+	"log"
+	"sysafari.com/customs/tguard/icp"
+	"time"
+)
+
+var dutyParty string
+var customsIds []string
+
+// vatnoteCmd represents the vatnote command
+var vatnoteCmd = &cobra.Command{
+	Use:   "vatnote",
+	Short: "生成税代指定月份的vat-note压缩包",
+	Long: `下载报关单的vat note 和 transfer doc 文件并压缩为zip，不生成ICP文件。
+未指定报关单时，使用税代在该月份的所有报关单。
+For example:
+
+1. 生成税代当月的vat-note：			tguard vatnote --duty-party BE0796544895
+2. 生成税代2024-09的vat-note：		tguard vatnote --duty-party BE0796544895 --month 2024-09
+3. 生成指定报关单的vat-note：		tguard vatnote --duty-party BE0796544895 --customs-id C1 --customs-id C2`,
+	Run: func(cmd *cobra.Command, args []string) {
+		fmt.Println("vatnote called")
+		if dutyParty == "" {
+			log.Panic("The duty party is required.")
+		}
+		if _, err := time.Parse(MonthFormatLayout, month); err != nil {
+			log.Panic("Date format error", err)
+		}
+
+		// Init database connection
+		initDatabase()
+
+		vatNote := icp.MakeVatNoteForDutyParty(dutyParty, month, customsIds)
+		if len(vatNote.Errors) > 0 {
+			log.Printf("Make vat-note zip for duty party %s in the month %s failed, errors: %v\n", dutyParty, month, vatNote.Errors)
+			return
+		}
+		log.Printf("Make vat-note zip for duty party %s in the month %s success, the filename: %s\n", dutyParty, month, vatNote.VatNoteZipFileName)
+		if vatNote.VatNoteOssKey != "" {
+			log.Printf("The vat-note zip is uploaded as %s\n", vatNote.VatNoteOssKey)
+		}
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(vatnoteCmd)
+
+	vatnoteCmd.Flags().StringVar(&dutyParty, "duty-party", "", "税代（duty party）")
+	vatnoteCmd.Flags().StringVar(&month, "month", time.Now().Format(MonthFormatLayout), "指定月份，默认为命令执行时当前月份(2006-01)")
+	vatnoteCmd.Flags().StringArrayVar(&customsIds, "customs-id", nil, "指定报关单，可重复指定")
+}
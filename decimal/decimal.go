@@ -0,0 +1,255 @@
+package decimal
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"math"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+const (
+	// Scale The number of decimal places kept by Decimal
+	Scale = 6
+	// unit The integer value of 1 in Decimal
+	unit = 1000000
+)
+
+// Decimal A fixed-point decimal number with 6 decimal places, used for money and weight.
+// The value is stored as an integer number of millionths, so additions are exact.
+type Decimal int64
+
+// Zero The decimal zero
+const Zero Decimal = 0
+
+// NewFromInt Create decimal from an integer
+func NewFromInt(i int64) Decimal {
+	return Decimal(i * unit)
+}
+
+// NewFromFloat Create decimal from a float, the value is rounded to 6 decimal places
+func NewFromFloat(f float64) Decimal {
+	return Decimal(math.Round(f * unit))
+}
+
+// Parse Parse decimal from a string like "-1234.56", the fractional part is rounded to 6 decimal places
+func Parse(s string) (Decimal, error) {
+	v := strings.TrimSpace(s)
+	if v == "" {
+		return Zero, fmt.Errorf("decimal: can not parse empty string")
+	}
+
+	neg := false
+	switch v[0] {
+	case '-':
+		neg, v = true, v[1:]
+	case '+':
+		v = v[1:]
+	}
+
+	intPart, fracPart := v, ""
+	if i := strings.IndexByte(v, '.'); i >= 0 {
+		intPart, fracPart = v[:i], v[i+1:]
+	}
+	if intPart == "" && fracPart == "" {
+		return Zero, fmt.Errorf("decimal: can not parse %q", s)
+	}
+	if !isDigits(intPart) || !isDigits(fracPart) {
+		return Zero, fmt.Errorf("decimal: can not parse %q", s)
+	}
+
+	var units int64
+	if intPart != "" {
+		i, err := strconv.ParseInt(intPart, 10, 64)
+		if err != nil || i > math.MaxInt64/unit {
+			return Zero, fmt.Errorf("decimal: %q out of range", s)
+		}
+		units = i * unit
+	}
+
+	// 超出精度的部分四舍五入
+	roundUp := false
+	if len(fracPart) > Scale {
+		roundUp = fracPart[Scale] >= '5'
+		fracPart = fracPart[:Scale]
+	}
+	if fracPart != "" {
+		fracPart += strings.Repeat("0", Scale-len(fracPart))
+		f, _ := strconv.ParseInt(fracPart, 10, 64)
+		units += f
+	}
+	if roundUp {
+		units++
+	}
+	// 整数部分接近上限时，加上小数部分会溢出
+	if units < 0 {
+		return Zero, fmt.Errorf("decimal: %q out of range", s)
+	}
+
+	if neg {
+		units = -units
+	}
+	return Decimal(units), nil
+}
+
+// isDigits Whether the string only contains digits
+func isDigits(s string) bool {
+	for _, c := range s {
+		if c < '0' || c > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// Add Returns d + o
+func (d Decimal) Add(o Decimal) Decimal {
+	return d + o
+}
+
+// Sub Returns d - o
+func (d Decimal) Sub(o Decimal) Decimal {
+	return d - o
+}
+
+// IsZero Whether the decimal is zero
+func (d Decimal) IsZero() bool {
+	return d == 0
+}
+
+// Round Round the decimal to the decimal places, half away from zero
+func (d Decimal) Round(places int) Decimal {
+	if places >= Scale {
+		return d
+	}
+	if places < 0 {
+		places = 0
+	}
+	p := int64(math.Pow10(Scale - places))
+	v := int64(d)
+	r := v % p
+	v -= r
+	if r*2 >= p {
+		v += p
+	} else if r*2 <= -p {
+		v -= p
+	}
+	return Decimal(v)
+}
+
+// Float64 The float value of the decimal, only used for display, never for calculation
+func (d Decimal) Float64() float64 {
+	return float64(d) / unit
+}
+
+// StringFixed The string of the decimal rounded to the decimal places, exp: "12.30"
+func (d Decimal) StringFixed(places int) string {
+	if places > Scale {
+		places = Scale
+	}
+	if places < 0 {
+		places = 0
+	}
+	v := int64(d.Round(places))
+	sign := ""
+	if v < 0 {
+		sign, v = "-", -v
+	}
+	intPart, fracPart := v/unit, v%unit
+	if places == 0 {
+		return fmt.Sprintf("%s%d", sign, intPart)
+	}
+	frac := fmt.Sprintf("%06d", fracPart)[:places]
+	return fmt.Sprintf("%s%d.%s", sign, intPart, frac)
+}
+
+// String The string of the decimal without trailing zeros, keep at least 2 decimal places
+func (d Decimal) String() string {
+	s := d.StringFixed(Scale)
+	s = strings.TrimRight(s, "0")
+	if i := strings.IndexByte(s, '.'); len(s)-i-1 < 2 {
+		s += strings.Repeat("0", 2-(len(s)-i-1))
+	}
+	return s
+}
+
+// Scan Implements the sql.Scanner interface, scan from MySQL DECIMAL column
+func (d *Decimal) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*d = Zero
+	case []byte:
+		p, err := Parse(string(v))
+		if err != nil {
+			return err
+		}
+		*d = p
+	case string:
+		p, err := Parse(v)
+		if err != nil {
+			return err
+		}
+		*d = p
+	case int64:
+		*d = NewFromInt(v)
+	case float64:
+		*d = NewFromFloat(v)
+	default:
+		return fmt.Errorf("decimal: can not scan %T into Decimal", src)
+	}
+	return nil
+}
+
+// Value Implements the driver.Valuer interface
+func (d Decimal) Value() (driver.Value, error) {
+	return d.StringFixed(Scale), nil
+}
+
+// Sum Returns the sum of the decimals
+func Sum(ds ...Decimal) Decimal {
+	var s Decimal
+	for _, d := range ds {
+		s += d
+	}
+	return s
+}
+
+// Mul Returns d * o, rounded to 6 decimal places. A result out of the Decimal range returns an error.
+func (d Decimal) Mul(o Decimal) (Decimal, error) {
+	r := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(int64(o)))
+	return fromUnits(roundQuo(r, big.NewInt(unit)), "%s * %s", d, o)
+}
+
+// Div Returns d / o, rounded to 6 decimal places.
+// Division by zero or a result out of the Decimal range returns an error.
+func (d Decimal) Div(o Decimal) (Decimal, error) {
+	if o == 0 {
+		return Zero, fmt.Errorf("decimal: division by zero")
+	}
+	r := new(big.Int).Mul(big.NewInt(int64(d)), big.NewInt(unit))
+	return fromUnits(roundQuo(r, big.NewInt(int64(o))), "%s / %s", d, o)
+}
+
+// fromUnits The decimal of the units, an error if the units overflow int64
+func fromUnits(units *big.Int, format string, d, o Decimal) (Decimal, error) {
+	if !units.IsInt64() {
+		return Zero, fmt.Errorf("decimal: "+format+" out of range", d, o)
+	}
+	return Decimal(units.Int64()), nil
+}
+
+// roundQuo Returns x / y, rounded half away from zero
+func roundQuo(x, y *big.Int) *big.Int {
+	q, r := new(big.Int).QuoRem(x, y, new(big.Int))
+	r2 := new(big.Int).Abs(r)
+	r2.Mul(r2, big.NewInt(2))
+	if r2.Cmp(new(big.Int).Abs(y)) >= 0 {
+		if (x.Sign() < 0) != (y.Sign() < 0) {
+			q.Sub(q, big.NewInt(1))
+		} else {
+			q.Add(q, big.NewInt(1))
+		}
+	}
+	return q
+}
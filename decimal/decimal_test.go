@@ -0,0 +1,176 @@
+package decimal
+
+import (
+	"math"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    Decimal
+		wantErr bool
+	}{
+		{in: "0", want: 0},
+		{in: "12", want: 12000000},
+		{in: "-1234.56", want: -1234560000},
+		{in: "+1.5", want: 1500000},
+		{in: " 7.25 ", want: 7250000},
+		{in: ".5", want: 500000},
+		{in: "3.", want: 3000000},
+		{in: "0.0000005", want: 1},
+		{in: "0.0000004", want: 0},
+		{in: "-0.0000005", want: -1},
+		{in: "1.9999995", want: 2000000},
+		{in: "9223372036854.775807", want: math.MaxInt64},
+		{in: "9223372036854.999999", wantErr: true},
+		{in: "9223372036855", wantErr: true},
+		{in: "", wantErr: true},
+		{in: "-", wantErr: true},
+		{in: ".", wantErr: true},
+		{in: "1.2.3", wantErr: true},
+		{in: "1e5", wantErr: true},
+		{in: "abc", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := Parse(tt.in)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Parse(%q) error = %v, wantErr %v", tt.in, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("Parse(%q) = %d, want %d", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestRound(t *testing.T) {
+	tests := []struct {
+		in     string
+		places int
+		want   string
+	}{
+		{in: "1.005", places: 2, want: "1.01"},
+		{in: "1.004999", places: 2, want: "1.00"},
+		{in: "-1.005", places: 2, want: "-1.01"},
+		{in: "-1.004999", places: 2, want: "-1.00"},
+		{in: "2.5", places: 0, want: "3"},
+		{in: "-2.5", places: 0, want: "-3"},
+		{in: "0.125", places: 2, want: "0.13"},
+		{in: "1.123456", places: 6, want: "1.123456"},
+		{in: "1.123456", places: 8, want: "1.123456"},
+		{in: "7.5", places: -1, want: "8"},
+	}
+	for _, tt := range tests {
+		d, err := Parse(tt.in)
+		if err != nil {
+			t.Fatalf("Parse(%q) error = %v", tt.in, err)
+		}
+		if got := d.Round(tt.places).StringFixed(tt.places); got != tt.want {
+			t.Errorf("%s.Round(%d) = %s, want %s", tt.in, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestString(t *testing.T) {
+	tests := []struct {
+		in   Decimal
+		want string
+	}{
+		{in: 0, want: "0.00"},
+		{in: NewFromInt(12), want: "12.00"},
+		{in: 1230000, want: "1.23"},
+		{in: 1234500, want: "1.2345"},
+		{in: -500000, want: "-0.50"},
+		{in: 1, want: "0.000001"},
+	}
+	for _, tt := range tests {
+		if got := tt.in.String(); got != tt.want {
+			t.Errorf("Decimal(%d).String() = %s, want %s", int64(tt.in), got, tt.want)
+		}
+	}
+}
+
+func TestMul(t *testing.T) {
+	tests := []struct {
+		a, b    Decimal
+		want    Decimal
+		wantErr bool
+	}{
+		{a: NewFromInt(3), b: 1500000, want: 4500000},
+		{a: -2500000, b: 4000000, want: -10000000},
+		// 0.000001 * 0.5 = 0.0000005，四舍五入为 0.000001
+		{a: 1, b: 500000, want: 1},
+		{a: -1, b: 500000, want: -1},
+		{a: 1, b: 499999, want: 0},
+		{a: NewFromInt(1000000000), b: NewFromInt(1000), want: NewFromInt(1000000000000)},
+		{a: NewFromInt(1000000000), b: NewFromInt(1000000000), wantErr: true},
+		{a: math.MaxInt64, b: NewFromInt(-2), wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Mul(tt.b)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s * %s error = %v, wantErr %v", tt.a, tt.b, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%s * %s = %s, want %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestDiv(t *testing.T) {
+	tests := []struct {
+		a, b    Decimal
+		want    Decimal
+		wantErr bool
+	}{
+		{a: NewFromInt(10), b: NewFromInt(4), want: 2500000},
+		{a: NewFromInt(1), b: NewFromInt(3), want: 333333},
+		{a: NewFromInt(2), b: NewFromInt(3), want: 666667},
+		{a: NewFromInt(-2), b: NewFromInt(3), want: -666667},
+		{a: NewFromInt(2), b: NewFromInt(-3), want: -666667},
+		{a: NewFromInt(-2), b: NewFromInt(-3), want: 666667},
+		// 0.000001 / 2 = 0.0000005，四舍五入为 0.000001
+		{a: 1, b: NewFromInt(2), want: 1},
+		{a: NewFromInt(1), b: 0, wantErr: true},
+		{a: NewFromInt(1000000000000), b: 1, wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := tt.a.Div(tt.b)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("%s / %s error = %v, wantErr %v", tt.a, tt.b, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && got != tt.want {
+			t.Errorf("%s / %s = %s, want %s", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
+func TestScan(t *testing.T) {
+	tests := []struct {
+		src     interface{}
+		want    Decimal
+		wantErr bool
+	}{
+		{src: nil, want: 0},
+		{src: []byte("12.345"), want: 12345000},
+		{src: "-0.5", want: -500000},
+		{src: int64(7), want: NewFromInt(7)},
+		{src: 1.25, want: 1250000},
+		{src: []byte("x"), wantErr: true},
+		{src: true, wantErr: true},
+	}
+	for _, tt := range tests {
+		var d Decimal
+		err := d.Scan(tt.src)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("Scan(%v) error = %v, wantErr %v", tt.src, err, tt.wantErr)
+			continue
+		}
+		if !tt.wantErr && d != tt.want {
+			t.Errorf("Scan(%v) = %s, want %s", tt.src, d, tt.want)
+		}
+	}
+}
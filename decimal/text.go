@@ -0,0 +1,76 @@
+package decimal
+
+import (
+	"database/sql/driver"
+	"fmt"
+	"strings"
+)
+
+// Text A column which holds either a decimal or a placeholder text, exp: "0.00" or "t.b.d."
+type Text struct {
+	Decimal Decimal
+	// Placeholder The text which is not a number, empty if the value is a decimal
+	Placeholder string
+}
+
+// NewText Create Text from the string, keep the string as placeholder if it is not a number
+func NewText(s string) Text {
+	d, err := Parse(s)
+	if err != nil {
+		return Text{Placeholder: strings.TrimSpace(s)}
+	}
+	return Text{Decimal: d}
+}
+
+// IsPlaceholder Whether the value is a placeholder instead of a number
+func (t Text) IsPlaceholder() bool {
+	return t.Placeholder != ""
+}
+
+// String The placeholder, or the decimal with 2 decimal places
+func (t Text) String() string {
+	if t.IsPlaceholder() {
+		return t.Placeholder
+	}
+	return t.Decimal.StringFixed(2)
+}
+
+// Scan Implements the sql.Scanner interface
+func (t *Text) Scan(src interface{}) error {
+	switch v := src.(type) {
+	case nil:
+		*t = Text{}
+	case []byte:
+		*t = NewText(string(v))
+	case string:
+		*t = NewText(v)
+	case int64, float64:
+		var d Decimal
+		if err := d.Scan(v); err != nil {
+			return err
+		}
+		*t = Text{Decimal: d}
+	default:
+		return fmt.Errorf("decimal: can not scan %T into Text", src)
+	}
+	return nil
+}
+
+// Value Implements the driver.Valuer interface
+func (t Text) Value() (driver.Value, error) {
+	return t.String(), nil
+}
+
+// SumText Returns the sum of the numeric values and the count of the placeholders skipped
+func SumText(ts ...Text) (Decimal, int) {
+	var s Decimal
+	placeholders := 0
+	for _, t := range ts {
+		if t.IsPlaceholder() {
+			placeholders++
+			continue
+		}
+		s += t.Decimal
+	}
+	return s, placeholders
+}
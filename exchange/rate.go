@@ -0,0 +1,145 @@
+package exchange
+
+import (
+	"encoding/csv"
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"sysafari.com/customs/tguard/decimal"
+	"sysafari.com/customs/tguard/global"
+	"time"
+)
+
+const (
+	// BaseCurrency All amounts are converted to EUR
+	BaseCurrency = "EUR"
+	// RateDateLayout The date layout of the rates
+	RateDateLayout = "2006-01-02"
+
+	SourceFile = "file"
+	SourceDB   = "db"
+)
+
+// Rate The exchange rate of the currency on the date, 1 EUR = Rate units of the currency (ECB convention)
+type Rate struct {
+	Date     string          `db:"rate_date"`
+	Currency string          `db:"currency"`
+	Rate     decimal.Decimal `db:"rate"`
+}
+
+// Table The exchange rates of the currencies keyed by date
+type Table struct {
+	// Source Where the rates are loaded from, exp: file:rates.csv
+	Source string
+	// rates The rates of every currency, sorted by date
+	rates map[string][]Rate
+}
+
+// NewTable Create the rate table with the rates
+func NewTable(source string, rates []Rate) (*Table, error) {
+	t := &Table{Source: source, rates: map[string][]Rate{}}
+	for _, r := range rates {
+		if _, err := time.Parse(RateDateLayout, r.Date); err != nil {
+			return nil, fmt.Errorf("The rate date: %s of %s invalid format(correct: 2006-01-02)", r.Date, r.Currency)
+		}
+		if r.Rate <= 0 {
+			return nil, fmt.Errorf("The rate of %s on %s must be positive, but was %s", r.Currency, r.Date, r.Rate)
+		}
+		currency := strings.ToUpper(strings.TrimSpace(r.Currency))
+		r.Currency = currency
+		t.rates[currency] = append(t.rates[currency], r)
+	}
+	for _, rs := range t.rates {
+		sort.Slice(rs, func(i, j int) bool { return rs[i].Date < rs[j].Date })
+	}
+	return t, nil
+}
+
+// Lookup Find the newest rate of the currency on or before the date
+func (t *Table) Lookup(currency string, date time.Time) (Rate, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" || currency == BaseCurrency {
+		return Rate{Date: date.Format(RateDateLayout), Currency: BaseCurrency, Rate: decimal.NewFromInt(1)}, nil
+	}
+
+	rs := t.rates[currency]
+	day := date.Format(RateDateLayout)
+	i := sort.Search(len(rs), func(i int) bool { return rs[i].Date > day })
+	if i == 0 {
+		return Rate{}, fmt.Errorf("No exchange rate of %s on or before %s in %s", currency, day, t.Source)
+	}
+	return rs[i-1], nil
+}
+
+// ToEUR Convert the amount in the currency to EUR with the rate on the date
+func (t *Table) ToEUR(amount decimal.Decimal, currency string, date time.Time) (decimal.Decimal, Rate, error) {
+	rate, err := t.Lookup(currency, date)
+	if err != nil {
+		return decimal.Zero, rate, err
+	}
+	eur, err := amount.Div(rate.Rate)
+	return eur, rate, err
+}
+
+// LoadFromFile Load the rates from the CSV file, every line: date,currency,rate. exp: 2024-09-01,USD,1.1104
+func LoadFromFile(path string) (*Table, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	reader := csv.NewReader(f)
+	reader.FieldsPerRecord = 3
+	reader.TrimLeadingSpace = true
+
+	var rates []Rate
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		// 跳过表头
+		if line == 1 && strings.EqualFold(record[0], "date") {
+			continue
+		}
+		rate, err := decimal.Parse(record[2])
+		if err != nil {
+			return nil, fmt.Errorf("%s line %d: %v", path, line, err)
+		}
+		rates = append(rates, Rate{Date: record[0], Currency: record[1], Rate: rate})
+	}
+	return NewTable(SourceFile+":"+path, rates)
+}
+
+// LoadFromDB Load the rates from the database table config_exchange_rate
+func LoadFromDB() (*Table, error) {
+	var rates []Rate
+	err := global.Db.Select(&rates, QueryExchangeRatesSql)
+	if err != nil {
+		return nil, err
+	}
+	return NewTable(SourceDB+":config_exchange_rate", rates)
+}
+
+// Load Load the rates from the source configured by exchange.source
+func Load() (*Table, error) {
+	switch source := viper.GetString("exchange.source"); source {
+	case SourceDB:
+		return LoadFromDB()
+	case SourceFile, "":
+		path := viper.GetString("exchange.file")
+		if path == "" {
+			return nil, fmt.Errorf("The exchange rate file(exchange.file) is not set")
+		}
+		return LoadFromFile(path)
+	default:
+		return nil, fmt.Errorf("Unsupported exchange rate source: %s", source)
+	}
+}
@@ -0,0 +1,125 @@
+package exchange
+
+import (
+	"os"
+	"path/filepath"
+	"sysafari.com/customs/tguard/decimal"
+	"testing"
+	"time"
+)
+
+func mustParse(t *testing.T, s string) decimal.Decimal {
+	t.Helper()
+	d, err := decimal.Parse(s)
+	if err != nil {
+		t.Fatalf("decimal.Parse(%q) error = %v", s, err)
+	}
+	return d
+}
+
+func day(s string) time.Time {
+	d, _ := time.Parse(RateDateLayout, s)
+	return d
+}
+
+func newTestTable(t *testing.T) *Table {
+	t.Helper()
+	table, err := NewTable("test", []Rate{
+		// 故意乱序，NewTable 需要按日期排序
+		{Date: "2024-10-01", Currency: "USD", Rate: mustParse(t, "1.1150")},
+		{Date: "2024-09-01", Currency: "usd", Rate: mustParse(t, "1.1104")},
+		{Date: "2024-09-01", Currency: "GBP", Rate: mustParse(t, "0.8450")},
+	})
+	if err != nil {
+		t.Fatalf("NewTable error = %v", err)
+	}
+	return table
+}
+
+func TestLookup(t *testing.T) {
+	table := newTestTable(t)
+	tests := []struct {
+		name     string
+		currency string
+		date     string
+		wantDate string
+		wantRate string
+		wantErr  bool
+	}{
+		{name: "exact date", currency: "USD", date: "2024-09-01", wantDate: "2024-09-01", wantRate: "1.1104"},
+		{name: "newest rate on or before the date", currency: "USD", date: "2024-09-30", wantDate: "2024-09-01", wantRate: "1.1104"},
+		{name: "next month", currency: "USD", date: "2024-10-15", wantDate: "2024-10-01", wantRate: "1.1150"},
+		{name: "lower case currency", currency: " usd ", date: "2024-10-01", wantDate: "2024-10-01", wantRate: "1.1150"},
+		{name: "base currency", currency: "EUR", date: "2024-09-10", wantDate: "2024-09-10", wantRate: "1"},
+		{name: "empty currency is base currency", currency: "", date: "2024-09-10", wantDate: "2024-09-10", wantRate: "1"},
+		{name: "before the first rate", currency: "USD", date: "2024-08-31", wantErr: true},
+		{name: "unknown currency", currency: "JPY", date: "2024-09-10", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := table.Lookup(tt.currency, day(tt.date))
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Lookup(%q, %s) error = %v, wantErr %v", tt.currency, tt.date, err, tt.wantErr)
+			}
+			if tt.wantErr {
+				return
+			}
+			if got.Date != tt.wantDate || got.Rate != mustParse(t, tt.wantRate) {
+				t.Errorf("Lookup(%q, %s) = %s %s, want %s %s", tt.currency, tt.date, got.Date, got.Rate, tt.wantDate, tt.wantRate)
+			}
+		})
+	}
+}
+
+func TestToEUR(t *testing.T) {
+	table := newTestTable(t)
+
+	eur, rate, err := table.ToEUR(mustParse(t, "111.04"), "USD", day("2024-09-15"))
+	if err != nil {
+		t.Fatalf("ToEUR error = %v", err)
+	}
+	if eur != decimal.NewFromInt(100) || rate.Date != "2024-09-01" {
+		t.Errorf("ToEUR = %s with rate of %s, want 100.00 with rate of 2024-09-01", eur, rate.Date)
+	}
+
+	if _, _, err = table.ToEUR(decimal.NewFromInt(1), "CHF", day("2024-09-15")); err == nil {
+		t.Errorf("ToEUR of unknown currency want error")
+	}
+}
+
+func TestNewTableInvalidRate(t *testing.T) {
+	if _, err := NewTable("test", []Rate{{Date: "2024/09/01", Currency: "USD", Rate: decimal.NewFromInt(1)}}); err == nil {
+		t.Errorf("NewTable with invalid date want error")
+	}
+	if _, err := NewTable("test", []Rate{{Date: "2024-09-01", Currency: "USD", Rate: decimal.Zero}}); err == nil {
+		t.Errorf("NewTable with zero rate want error")
+	}
+}
+
+func TestLoadFromFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "rates.csv")
+	content := "date,currency,rate\n2024-09-01,USD,1.1104\n2024-09-01, GBP, 0.8450\n"
+	if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	table, err := LoadFromFile(path)
+	if err != nil {
+		t.Fatalf("LoadFromFile error = %v", err)
+	}
+	if table.Source != SourceFile+":"+path {
+		t.Errorf("Source = %s, want %s", table.Source, SourceFile+":"+path)
+	}
+	rate, err := table.Lookup("GBP", day("2024-09-20"))
+	if err != nil || rate.Rate != mustParse(t, "0.8450") {
+		t.Errorf("Lookup GBP = %s, %v, want 0.8450", rate.Rate, err)
+	}
+
+	bad := filepath.Join(t.TempDir(), "bad.csv")
+	if err = os.WriteFile(bad, []byte("2024-09-01,USD,abc\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = LoadFromFile(bad); err == nil {
+		t.Errorf("LoadFromFile with invalid rate want error")
+	}
+}
@@ -0,0 +1,8 @@
+package exchange
+
+const (
+	// QueryExchangeRatesSql Query all exchange rates against EUR
+	QueryExchangeRatesSql = `SELECT DATE_FORMAT(rate_date, '%Y-%m-%d') AS rate_date, currency, rate
+FROM config_exchange_rate
+ORDER BY currency, rate_date;`
+)
@@ -1,25 +1,156 @@
 package global
 
 import (
+	"context"
 	"fmt"
+	"github.com/go-sql-driver/mysql"
 	"github.com/jmoiron/sqlx"
 	"github.com/spf13/viper"
+	"net"
+	"strconv"
+	"sync"
 	"time"
 )
 
-// InitGlobalDatabaseConnection sets the global database connection
-func InitGlobalDatabaseConnection() {
-	fmt.Println("init sql connection ....")
-	db, err := sqlx.Open("mysql", viper.GetString("mysql.url"))
+// dbMu Guards the initialization of the global connections
+var dbMu sync.Mutex
+
+// setDatabaseDefaults The defaults of the mysql.* settings, the durations are in seconds unless noted
+func setDatabaseDefaults() {
+	viper.SetDefault("mysql.driver", "mysql")
+	// minutes
+	viper.SetDefault("mysql.max-life-time", 3)
+	viper.SetDefault("mysql.max-idle-time", 0)
+	viper.SetDefault("mysql.max-open-connections", 10)
+	viper.SetDefault("mysql.max-idle-connections", 10)
+	viper.SetDefault("mysql.port", 3306)
+	viper.SetDefault("mysql.connect-timeout", 10)
+	viper.SetDefault("mysql.read-timeout", 0)
+	viper.SetDefault("mysql.write-timeout", 0)
+	viper.SetDefault("mysql.ping-retries", 3)
+	viper.SetDefault("mysql.ping-backoff", 2)
+	viper.SetDefault("mysql.query-timeout", 300)
+}
+
+// databaseSetting The setting under the key, falls back to the mysql.* one, so the replica only sets what differs
+func databaseSetting(key string, name string) string {
+	if viper.IsSet(key + "." + name) {
+		return key + "." + name
+	}
+	return "mysql." + name
+}
+
+// databaseDSN The DSN of the settings under the key, the url is used if set, otherwise it is built from
+// host, port, user, password, database and params, exp: mysql.url or mysql.host, mysql.replica.url
+func databaseDSN(key string) string {
+	if url := viper.GetString(key + ".url"); url != "" {
+		return url
+	}
+	host := viper.GetString(key + ".host")
+	if host == "" {
+		return ""
+	}
+
+	cfg := mysql.NewConfig()
+	cfg.Net = "tcp"
+	cfg.Addr = net.JoinHostPort(host, strconv.Itoa(viper.GetInt(databaseSetting(key, "port"))))
+	cfg.User = viper.GetString(databaseSetting(key, "user"))
+	cfg.Passwd = viper.GetString(databaseSetting(key, "password"))
+	cfg.DBName = viper.GetString(databaseSetting(key, "database"))
+	cfg.Params = viper.GetStringMapString(databaseSetting(key, "params"))
+	cfg.Timeout = time.Duration(viper.GetInt(databaseSetting(key, "connect-timeout"))) * time.Second
+	cfg.ReadTimeout = time.Duration(viper.GetInt(databaseSetting(key, "read-timeout"))) * time.Second
+	cfg.WriteTimeout = time.Duration(viper.GetInt(databaseSetting(key, "write-timeout"))) * time.Second
+	return cfg.FormatDSN()
+}
+
+// openDatabase Open the connection pool with the pool settings under the key and ping it with retries,
+// the settings not set under the key fall back to the mysql.* ones
+func openDatabase(name string, key string, dsn string) (*sqlx.DB, error) {
+	db, err := sqlx.Open(viper.GetString("mysql.driver"), dsn)
+	if err != nil {
+		return nil, fmt.Errorf("open %s database failed: %v", name, err)
+	}
+	db.SetConnMaxLifetime(time.Duration(viper.GetInt(databaseSetting(key, "max-life-time"))) * time.Minute)
+	db.SetConnMaxIdleTime(time.Duration(viper.GetInt(databaseSetting(key, "max-idle-time"))) * time.Minute)
+	db.SetMaxOpenConns(viper.GetInt(databaseSetting(key, "max-open-connections")))
+	db.SetMaxIdleConns(viper.GetInt(databaseSetting(key, "max-idle-connections")))
+
+	retries := viper.GetInt(databaseSetting(key, "ping-retries"))
+	backoff := time.Duration(viper.GetInt(databaseSetting(key, "ping-backoff"))) * time.Second
+	timeout := time.Duration(viper.GetInt(databaseSetting(key, "connect-timeout"))) * time.Second
+	for attempt := 0; ; attempt++ {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		err = db.PingContext(ctx)
+		cancel()
+		if err == nil {
+			break
+		}
+		if attempt >= retries {
+			_ = db.Close()
+			return nil, fmt.Errorf("ping %s database failed after %d attempts: %v", name, attempt+1, err)
+		}
+		fmt.Printf("Ping %s database failed, retry in %v: %v\n", name, backoff*time.Duration(attempt+1), err)
+		time.Sleep(backoff * time.Duration(attempt+1))
+	}
+	fmt.Printf("%s db stats: %+v\n", name, db.Stats())
+	return db, nil
+}
+
+// InitGlobalDatabaseConnection sets the global database connection and the read replica.
+// The connections are initialized once, the later calls do nothing, an error is returned if the database is unavailable.
+func InitGlobalDatabaseConnection() error {
+	dbMu.Lock()
+	defer dbMu.Unlock()
+	if Db != nil {
+		return nil
+	}
 
+	fmt.Println("init sql connection ....")
+	setDatabaseDefaults()
+	dsn := databaseDSN("mysql")
+	if dsn == "" {
+		return fmt.Errorf("mysql.url or mysql.host is required")
+	}
+	db, err := openDatabase("primary", "mysql", dsn)
 	if err != nil {
-		panic(err)
+		return err
+	}
+
+	// 只读副本可选，未配置或不可用时读取主库
+	readDb := db
+	if replicaDsn := databaseDSN("mysql.replica"); replicaDsn != "" {
+		replica, err := openDatabase("replica", "mysql.replica", replicaDsn)
+		if err != nil {
+			fmt.Printf("The read replica is unavailable, read from the primary database: %v\n", err)
+		} else {
+			readDb = replica
+		}
 	}
-	// See "Important settings" section.
-	db.SetConnMaxLifetime(time.Minute * 3)
-	db.SetMaxOpenConns(10)
-	db.SetMaxIdleConns(10)
-	fmt.Println("db stats:", db.Stats())
 
-	Db = db
+	Db, ReadDb = db, readDb
+	return nil
+}
+
+// QueryContext The context of one query with the mysql.query-timeout, 0 is no timeout
+func QueryContext() (context.Context, context.CancelFunc) {
+	timeout := time.Duration(viper.GetInt("mysql.query-timeout")) * time.Second
+	if timeout <= 0 {
+		return context.WithCancel(context.Background())
+	}
+	return context.WithTimeout(context.Background(), timeout)
+}
+
+// Select Query the rows into dest with the query timeout
+func Select(db *sqlx.DB, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := QueryContext()
+	defer cancel()
+	return db.SelectContext(ctx, dest, query, args...)
+}
+
+// Get Query one row into dest with the query timeout
+func Get(db *sqlx.DB, dest interface{}, query string, args ...interface{}) error {
+	ctx, cancel := QueryContext()
+	defer cancel()
+	return db.GetContext(ctx, dest, query, args...)
 }
@@ -0,0 +1,42 @@
+package global
+
+import (
+	"github.com/go-sql-driver/mysql"
+	"github.com/spf13/viper"
+	"testing"
+	"time"
+)
+
+func TestReplicaDSNOverridesTimeouts(t *testing.T) {
+	setDatabaseDefaults()
+	viper.Set("mysql.host", "primary")
+	viper.Set("mysql.read-timeout", 30)
+	viper.Set("mysql.replica.host", "replica")
+	viper.Set("mysql.replica.read-timeout", 120)
+	defer func() {
+		for _, key := range []string{"mysql.host", "mysql.read-timeout", "mysql.replica.host", "mysql.replica.read-timeout"} {
+			viper.Set(key, nil)
+		}
+	}()
+
+	tests := []struct {
+		key         string
+		addr        string
+		readTimeout time.Duration
+		timeout     time.Duration
+	}{
+		{"mysql", "primary:3306", 30 * time.Second, 10 * time.Second},
+		// 副本未配置的参数沿用 mysql.*
+		{"mysql.replica", "replica:3306", 120 * time.Second, 10 * time.Second},
+	}
+	for _, tt := range tests {
+		cfg, err := mysql.ParseDSN(databaseDSN(tt.key))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if cfg.Addr != tt.addr || cfg.ReadTimeout != tt.readTimeout || cfg.Timeout != tt.timeout {
+			t.Errorf("%s: addr/read timeout/timeout = %s/%v/%v, want %s/%v/%v", tt.key,
+				cfg.Addr, cfg.ReadTimeout, cfg.Timeout, tt.addr, tt.readTimeout, tt.timeout)
+		}
+	}
+}
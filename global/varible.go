@@ -6,3 +6,6 @@ import (
 
 // Db Global db connection
 var Db *sqlx.DB
+
+// ReadDb The connection of the read replica used by the heavy ICP and audit queries, it is Db if no replica is configured
+var ReadDb *sqlx.DB
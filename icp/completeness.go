@@ -0,0 +1,245 @@
+package icp
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/xuri/excelize/v2"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sysafari.com/customs/tguard/global"
+	"sysafari.com/customs/tguard/icp/script"
+	"sysafari.com/customs/tguard/utils"
+	"sysafari.com/customs/tguard/workbook"
+	"time"
+)
+
+const (
+	// IssueNoPod The tracking has logistics info but no POD file
+	IssueNoPod = "NO_POD"
+	// IssueNoLogistics The tracking has no logistics info
+	IssueNoLogistics = "NO_LOGISTICS"
+	// IssueNoTax The customs has only TMP_TAX, no TAX
+	IssueNoTax = "NO_TAX"
+	// IssueNoTaxProcess The customs has neither TAX nor TMP_TAX
+	IssueNoTaxProcess = "NO_TAX_PROCESS"
+	// IssueNoTaxReceipt The customs has no tax receipt link, exp: the declare country is not NL or BE
+	IssueNoTaxReceipt = "NO_TAX_RECEIPT"
+
+	// CompletenessFileSuffix The suffix of the completeness report, exp: 2024-09-BE0796544895-completeness.xlsx
+	CompletenessFileSuffix = "-completeness"
+)
+
+// CompletenessIssue A missing document or data which makes the ICP rejected by agencies
+type CompletenessIssue struct {
+	DutyParty      string `json:"duty_party"`
+	CustomsId      string `json:"customs_id"`
+	Mrn            string `json:"mrn"`
+	BillNo         string `json:"bill_no"`
+	DeclareCountry string `json:"declare_country"`
+	TrackingNo     string `json:"tracking_no"`
+	Issue          string `json:"issue"`
+}
+
+// CompletenessReport The completeness report of the customs in the month
+type CompletenessReport struct {
+	Month     string `json:"month"`
+	DutyParty string `json:"duty_party"`
+	// FileName The xlsx report file name, the JSON report has the same name with .json extension
+	FileName     string              `json:"file_name"`
+	CustomsTotal int                 `json:"customs_total"`
+	Issues       []CompletenessIssue `json:"issues"`
+	Errors       []string            `json:"errors"`
+}
+
+// trackingLogistics Whether the tracking has logistics info
+type trackingLogistics struct {
+	TrackingNo   string `db:"tracking_no"`
+	HasLogistics bool   `db:"has_logistics"`
+}
+
+// CompletenessFilePath The full path of the completeness report, it is saved in the ICP directory of the month
+func CompletenessFilePath(fileName string) (string, error) {
+	ext := filepath.Ext(fileName)
+	if len(fileName) < 7 || (ext != ".xlsx" && ext != ".json") || !strings.HasSuffix(strings.TrimSuffix(fileName, ext), CompletenessFileSuffix) {
+		return "", fmt.Errorf("The completeness report filename:%s invalid format(correct: 2006-01-BE0796544895%s.xlsx)", fileName, CompletenessFileSuffix)
+	}
+	monthDt, err := time.Parse("2006-01", fileName[:7])
+	if err != nil {
+		return "", fmt.Errorf("The completeness report filename:%s invalid format(correct: 2006-01-BE0796544895%s.xlsx)", fileName, CompletenessFileSuffix)
+	}
+	return filepath.Join(viper.GetString("icp.save-dir"), monthDt.Format("2006"), monthDt.Format("01"), fileName), nil
+}
+
+// MakeCompletenessReport Check the POD and tax receipt of the customs in the month, the duty party is optional.
+// The report is saved as xlsx and JSON.
+func MakeCompletenessReport(month string, dutyParty string) *CompletenessReport {
+	r := &CompletenessReport{Month: month, DutyParty: dutyParty}
+	// 月份用于文件名和保存目录，格式错误时不生成报告
+	if _, err := time.Parse("2006-01", month); err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("The month:%s invalid format(correct: 2006-01).", month))
+		return r
+	}
+	r.FileName = month + CompletenessFileSuffix + ".xlsx"
+	if dutyParty != "" {
+		r.FileName = fmt.Sprintf("%s-%s%s.xlsx", month, dutyParty, CompletenessFileSuffix)
+	}
+
+	dutyParties := []string{dutyParty}
+	if dutyParty == "" {
+		dutyParties = nil
+		if err := global.Select(global.ReadDb, &dutyParties, script.QueryDutyPartiesForMonth, month); err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("Query duty parties of the month %s failed: %v", month, err))
+			return r
+		}
+	}
+
+	for _, dp := range dutyParties {
+		var ids []string
+		// 与生成ICP相同，排除拆分报关的子报关单
+		if err := global.Select(global.ReadDb, &ids, script.QueryCustomsByDutyPartyForMonthAfterSplitSql, dp, month); err != nil {
+			r.Errors = append(r.Errors, fmt.Sprintf("Query customs of duty party %s in the month %s failed: %v", dp, month, err))
+			continue
+		}
+		log.Printf("Checking completeness of %d customs of duty party %s \n", len(ids), dp)
+		for _, id := range ids {
+			r.checkCustoms(dp, id)
+		}
+		r.CustomsTotal += len(ids)
+	}
+
+	r.save()
+	return r
+}
+
+// checkCustoms Check the POD and tax receipt of the customs with the same rules as the ICP
+func (r *CompletenessReport) checkCustoms(dutyParty string, customsId string) {
+	var base CustomsICPBase
+	if err := global.Get(global.ReadDb, &base, script.QueryCustomsICPBaseSql, customsId); err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("The customs_id:%s query icp base info failed. %v", customsId, err))
+		return
+	}
+	issue := func(trackingNo string, kind string) {
+		r.Issues = append(r.Issues, CompletenessIssue{
+			DutyParty:      dutyParty,
+			CustomsId:      customsId,
+			Mrn:            base.Mrn,
+			BillNo:         base.BillNo,
+			DeclareCountry: base.DeclareCountry,
+			TrackingNo:     trackingNo,
+			Issue:          kind,
+		})
+	}
+
+	// 税单: 只有TMP_TAX 的报关单没有正式税单，TAX和TMP_TAX都没有的单独列出
+	var processCodes []string
+	if err := global.Select(global.ReadDb, &processCodes, script.QueryCustomsTaxProcessCodesSql, customsId); err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("The customs_id:%s query tax process failed. %v", customsId, err))
+		return
+	}
+	hasTax, hasTmpTax := utils.In(ProcessCodeTax, processCodes), utils.In(ProcessCodeTemTax, processCodes)
+	icp := &CustomsICP{CustomsId: customsId, Mrn: base.Mrn, DeclareCountry: base.DeclareCountry, ProcessCode: ProcessCodeTemTax}
+	switch {
+	case hasTax:
+		icp.ProcessCode = ProcessCodeTax
+	case hasTmpTax:
+		issue("", IssueNoTax)
+	default:
+		issue("", IssueNoTaxProcess)
+	}
+	icp.queryTaxFileData()
+	if len(icp.TaxFileData) == 0 {
+		issue("", IssueNoTaxReceipt)
+	}
+
+	// POD: 没有物流信息的运单单独列出
+	var logistics []trackingLogistics
+	if err := global.Select(global.ReadDb, &logistics, script.QueryCustomsTrackingLogisticsSql, customsId); err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("The customs_id:%s query tracking logistics failed. %v", customsId, err))
+		return
+	}
+	noLogistics := map[string]bool{}
+	for _, l := range logistics {
+		if !l.HasLogistics {
+			noLogistics[l.TrackingNo] = true
+		}
+	}
+	icp.queryPodFileData()
+	r.Errors = append(r.Errors, icp.Errors...)
+	for _, pod := range icp.PodFileData {
+		switch {
+		case noLogistics[pod.TrackingNo]:
+			issue(pod.TrackingNo, IssueNoLogistics)
+		case pod.PodFileLink.String == "":
+			issue(pod.TrackingNo, IssueNoPod)
+		}
+	}
+}
+
+// save Save the report as xlsx and JSON
+func (r *CompletenessReport) save() {
+	filePath, err := CompletenessFilePath(r.FileName)
+	if err != nil {
+		r.Errors = append(r.Errors, err.Error())
+		return
+	}
+	saveDir := filepath.Dir(filePath)
+	if !utils.IsDir(saveDir) && !utils.CreateDir(saveDir) {
+		r.Errors = append(r.Errors, fmt.Sprintf("Create save dir: %s failed.", saveDir))
+		return
+	}
+
+	file := excelize.NewFile()
+	if err = FillCompletenessSheet(file, "COMPLETENESS", r.Issues); err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("Fill completeness sheet failed: %v", err))
+		return
+	}
+	if err = file.SaveAs(filePath); err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("Save completeness report %s failed: %v", filePath, err))
+		return
+	}
+
+	content, err := json.MarshalIndent(r, "", "  ")
+	if err == nil {
+		err = os.WriteFile(strings.TrimSuffix(filePath, ".xlsx")+".json", content, 0644)
+	}
+	if err != nil {
+		r.Errors = append(r.Errors, fmt.Sprintf("Save completeness report JSON failed: %v", err))
+	}
+}
+
+// FillCompletenessSheet fill the completeness issues sheet
+func FillCompletenessSheet(file *excelize.File, sheetName string, issues []CompletenessIssue) error {
+	log.Println("Completeness sheet name: ", sheetName)
+	profile := workbook.LoadProfile()
+	file.NewSheet(sheetName)
+
+	headers := &[]interface{}{"SN", "Duty Party", "Bill No.", "Invoice Number", "MRN No.", "Declare Country", "Tracing No.", "Issue"}
+
+	err := file.SetSheetRow(sheetName, "A1", headers)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+	for i, datum := range issues {
+		sn := i + 1
+		idx := sn + 1
+
+		err = file.SetCellInt(sheetName, fmt.Sprintf("A%d", idx), sn)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("B%d", idx), datum.DutyParty)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("C%d", idx), datum.BillNo)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("D%d", idx), datum.CustomsId)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("E%d", idx), datum.Mrn)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("F%d", idx), datum.DeclareCountry)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("G%d", idx), datum.TrackingNo)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("H%d", idx), datum.Issue)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return profile.ApplySheet(file, sheetName)
+}
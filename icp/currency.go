@@ -0,0 +1,56 @@
+package icp
+
+import (
+	"fmt"
+	"strings"
+	"sysafari.com/customs/tguard/decimal"
+	"sysafari.com/customs/tguard/exchange"
+	"time"
+)
+
+// InvoiceDateLayout The layout of the invoice date in tax data
+const InvoiceDateLayout = "2006/01/02"
+
+// convertToEUR Fill the exchange rate and EUR value of the tax data with their original currency and amount.
+// The rate table is only loaded if there is tax data declared in other currencies than EUR,
+// returns the source of the rates and the conversion errors.
+func convertToEUR(taxData []TaxObject) (string, []string) {
+	var errs []string
+	var table *exchange.Table
+	rateSource := ""
+
+	for i := range taxData {
+		t := &taxData[i]
+		if t.OriginalCurrency == "" {
+			// 老版本数据没有原始币种，使用申报币种和金额
+			t.OriginalCurrency, t.OriginalAmount = t.Currency, t.LocalCurrencyValue
+		}
+		currency := strings.ToUpper(t.OriginalCurrency)
+		if currency == "" || currency == exchange.BaseCurrency {
+			t.OriginalCurrency = exchange.BaseCurrency
+			t.ExchangeRate, t.EURValue = decimal.NewFromInt(1), t.OriginalAmount
+			continue
+		}
+
+		if table == nil {
+			tb, err := exchange.Load()
+			if err != nil {
+				return rateSource, append(errs, fmt.Sprintf("Load exchange rates failed: %v", err))
+			}
+			table, rateSource = tb, tb.Source
+		}
+
+		date, err := time.Parse(InvoiceDateLayout, t.InvoiceDate)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("The customs_id:%s invoice date %s invalid format.", t.CustomsId, t.InvoiceDate))
+			continue
+		}
+		eur, rate, err := table.ToEUR(t.OriginalAmount, currency, date)
+		if err != nil {
+			errs = append(errs, fmt.Sprintf("The customs_id:%s convert %s to EUR failed: %v", t.CustomsId, currency, err))
+			continue
+		}
+		t.ExchangeRate, t.EURValue = rate.Rate, eur
+	}
+	return rateSource, errs
+}
@@ -2,16 +2,15 @@ package icp
 
 import (
 	"fmt"
-	"strings"
+	"github.com/spf13/viper"
+	"log"
 	"sysafari.com/customs/tguard/global"
 	"sysafari.com/customs/tguard/icp/script"
 )
 
 const (
-	ProcessCodeTax        = "TAX"
-	ProcessCodeTemTax     = "TMP_TAX"
-	TaxFileUrlPrefixForNL = "https://board.sysafari.com/declarefile/-1/18-"
-	TaxFileUrlPrefixForBE = "https://board.sysafari.com/declarefile-be?customsId=CUSTOMS_ID&statusCode=09"
+	ProcessCodeTax    = "TAX"
+	ProcessCodeTemTax = "TMP_TAX"
 )
 
 // CustomsICP 生成ICP表格文件，主要是ICP Excel文件的制作，不包含后续压缩包和存储路径等的操作
@@ -38,7 +37,7 @@ func (icp *CustomsICP) QueryFillData() {
 func (icp *CustomsICP) queryTaxData() {
 	// base info
 	var icpBase CustomsICPBase
-	err := global.Db.Get(&icpBase, script.QueryCustomsICPBaseSql, icp.CustomsId)
+	err := global.Get(global.ReadDb, &icpBase, script.QueryCustomsICPBaseSql, icp.CustomsId)
 	if err != nil {
 		icp.Errors = append(icp.Errors, fmt.Sprintf("The customs_id:%s query icp base info failed. %v", icp.CustomsId, err))
 	}
@@ -46,7 +45,7 @@ func (icp *CustomsICP) queryTaxData() {
 
 	// 查询当前customs 是否是拆分报关 has_split
 	var hasSplit bool
-	err = global.Db.Get(&hasSplit, script.QueryCustomsHasSplitSql, icp.CustomsId)
+	err = global.Get(global.ReadDb, &hasSplit, script.QueryCustomsHasSplitSql, icp.CustomsId)
 	if err != nil {
 		fmt.Println("Query customs has split failed, continue to make as no-split", err, icp.CustomsId)
 	}
@@ -60,21 +59,21 @@ func (icp *CustomsICP) queryTaxData() {
 
 	// tax info, 如果没有正式税则信息（TAX），则查询临时税则信息（TMP_TAX）
 	var taxInfo []CustomsICPTax
-	err = global.Db.Select(&taxInfo, queryCustomsTaxSql, icp.CustomsId, ProcessCodeTax)
+	err = global.Select(global.ReadDb, &taxInfo, queryCustomsTaxSql, icp.CustomsId, ProcessCodeTax)
 	if err != nil || len(taxInfo) == 0 {
 		// 查询临时税金信息
 		fmt.Printf("The customs_id:%s query TAX info failed, try to query TMP_TAX info.\n", icp.CustomsId)
-		err = global.Db.Select(&taxInfo, queryCustomsTaxSql, icp.CustomsId, ProcessCodeTemTax)
+		err = global.Select(global.ReadDb, &taxInfo, queryCustomsTaxSql, icp.CustomsId, ProcessCodeTemTax)
 	}
 
 	if err != nil || len(taxInfo) == 0 {
 		// Query none-ec sql tax information is not available
-		err = global.Db.Select(&taxInfo, script.QueryCustomsICPTaxSqlNoneEc, icp.CustomsId, ProcessCodeTax)
+		err = global.Select(global.ReadDb, &taxInfo, script.QueryCustomsICPTaxSqlNoneEc, icp.CustomsId, ProcessCodeTax)
 	}
 
 	if err != nil || len(taxInfo) == 0 {
 		// Query temporary tax information if official tax information is not available
-		err = global.Db.Select(&taxInfo, script.QueryCustomsICPTaxSqlNoneEc, icp.CustomsId, ProcessCodeTemTax)
+		err = global.Select(global.ReadDb, &taxInfo, script.QueryCustomsICPTaxSqlNoneEc, icp.CustomsId, ProcessCodeTemTax)
 	}
 
 	if err != nil || len(taxInfo) == 0 {
@@ -85,28 +84,28 @@ func (icp *CustomsICP) queryTaxData() {
 
 	// importer info
 	var importerInfo CustomsICPImporter
-	err = global.Db.Get(&importerInfo, script.QueryCustomsICPImporterSql, icp.CustomsId)
+	err = global.Get(global.ReadDb, &importerInfo, script.QueryCustomsICPImporterSql, icp.CustomsId)
 	if err != nil {
 		icp.Errors = append(icp.Errors, fmt.Sprintf("The customs_id:%s query importer info failed.%v", icp.CustomsId, err))
 	}
 
 	// delivery info
 	var deliveryInfo CustomsICPDelivery
-	err = global.Db.Get(&deliveryInfo, script.QueryCustomsICPDeliverySql, icp.CustomsId)
+	err = global.Get(global.ReadDb, &deliveryInfo, script.QueryCustomsICPDeliverySql, icp.CustomsId)
 	if err != nil {
 		icp.Errors = append(icp.Errors, fmt.Sprintf("The customs_id:%s query delivery address info failed.%v", icp.CustomsId, err))
 	}
 
 	// Company info
 	var companyName string
-	err = global.Db.Get(&companyName, script.QueryCustomsCompanySql, icp.CustomsId)
+	err = global.Get(global.ReadDb, &companyName, script.QueryCustomsCompanySql, icp.CustomsId)
 	if err != nil {
 		icp.Errors = append(icp.Errors, fmt.Sprintf("The customs_id:%s query company name failed.%v", icp.CustomsId, err))
 	}
 
 	// Query customs has inspection fine
 	var inspectionFineCount int64
-	err = global.Db.Get(&inspectionFineCount, script.QueryCustomsHasInspectionFineSql, icp.CustomsId)
+	err = global.Get(global.ReadDb, &inspectionFineCount, script.QueryCustomsHasInspectionFineSql, icp.CustomsId)
 	if err != nil {
 		icp.Errors = append(icp.Errors, fmt.Sprintf("The customs_id:%s query inspection fine failed.%v", icp.CustomsId, err))
 	}
@@ -154,6 +153,8 @@ func combineToTaxData(baseInfo CustomsICPBase, taxInfo []CustomsICPTax, importer
 			Quantity:             tax.Quantity,
 			Description:          tax.Description,
 			Currency:             tax.Currency,
+			OriginalCurrency:     tax.OriginalCurrency,
+			OriginalAmount:       tax.OriginalAmount,
 			VatNo:                importerInfo.VatNo.String,
 			EoriNo:               importerInfo.EoriNo,
 			ImportAddressCode:    importerInfo.ImportAddressCode,
@@ -178,41 +179,43 @@ func (icp *CustomsICP) queryTaxFileData() {
 	if "TAX" == icp.ProcessCode {
 		taxType = 4
 	}
-	if "NL" == icp.DeclareCountry {
-		tf := TaxFileObject{
-			Mrn:         icp.Mrn,
-			CustomsId:   icp.CustomsId,
-			TaxType:     taxType,
-			TaxFileLink: TaxFileUrlPrefixForNL + icp.CustomsId,
+	link, err := TaxReceiptLink(TaxReceiptLinkParams{
+		CustomsId:      icp.CustomsId,
+		Mrn:            icp.Mrn,
+		DeclareCountry: icp.DeclareCountry,
+		TaxType:        taxType,
+	})
+	if err != nil {
+		// 没有税单链接的报关单不影响ICP生成，除非配置为严格模式
+		log.Printf("Warning: the customs_id:%s has no tax receipt link, %v\n", icp.CustomsId, err)
+		if viper.GetBool("icp.tax-receipt-link-strict") {
+			icp.Errors = append(icp.Errors, fmt.Sprintf("The customs_id:%s has no tax receipt link, %v", icp.CustomsId, err))
 		}
-		icp.TaxFileData = append(icp.TaxFileData, tf)
+		return
 	}
 
-	if "BE" == icp.DeclareCountry {
-		tf := TaxFileObject{
-			Mrn:         icp.Mrn,
-			CustomsId:   icp.CustomsId,
-			TaxType:     taxType,
-			TaxFileLink: strings.ReplaceAll(TaxFileUrlPrefixForBE, "CUSTOMS_ID", icp.CustomsId),
-		}
-		icp.TaxFileData = append(icp.TaxFileData, tf)
+	tf := TaxFileObject{
+		Mrn:         icp.Mrn,
+		CustomsId:   icp.CustomsId,
+		TaxType:     taxType,
+		TaxFileLink: link,
 	}
-
+	icp.TaxFileData = append(icp.TaxFileData, tf)
 }
 
 // queryPodFileData Query the fill data of the pod file table
 func (icp *CustomsICP) queryPodFileData() {
 	var customsServiceKey CustomsServiceKeyObject
-	err := global.Db.Get(&customsServiceKey, script.QueryCustomsServiceKeySql, icp.CustomsId)
+	err := global.Get(global.ReadDb, &customsServiceKey, script.QueryCustomsServiceKeySql, icp.CustomsId)
 	if err != nil {
 		icp.Errors = append(icp.Errors, fmt.Sprintf("The customs_id:%s query service_key  failed.%v", icp.CustomsId, err))
 	}
 
 	var podFiles []PodFileObject
 	if "DECLARATION ONLY" == customsServiceKey.ServiceKey {
-		err = global.Db.Select(&podFiles, script.QueryCustomsTrackingPodDeclareOnlySql, icp.CustomsId)
+		err = global.Select(global.ReadDb, &podFiles, script.QueryCustomsTrackingPodDeclareOnlySql, icp.CustomsId)
 	} else {
-		err = global.Db.Select(&podFiles, script.QueryCustomsTrackingPodSql, icp.CustomsId)
+		err = global.Select(global.ReadDb, &podFiles, script.QueryCustomsTrackingPodSql, icp.CustomsId)
 	}
 
 	if err != nil {
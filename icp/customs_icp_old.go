@@ -44,11 +44,18 @@ func (icp *CustomsICPOld) queryTaxData() {
 func (icp *CustomsICPOld) queryTaxFileData() {
 	if len(icp.TaxData) > 0 {
 		first := icp.TaxData[0]
+		// 旧报关单都在NL申报
+		link, _ := TaxReceiptLink(TaxReceiptLinkParams{
+			CustomsId:      icp.CustomsId,
+			Mrn:            first.Mrn,
+			DeclareCountry: "NL",
+			TaxType:        first.ProcessStatus,
+		})
 		tf := TaxFileObject{
 			Mrn:         first.Mrn,
 			CustomsId:   icp.CustomsId,
 			TaxType:     first.ProcessStatus,
-			TaxFileLink: TaxFileUrlPrefixForNL + icp.CustomsId,
+			TaxFileLink: link,
 		}
 		icp.TaxFileData = append(icp.TaxFileData, tf)
 	} else {
@@ -0,0 +1,208 @@
+package icp
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"sync"
+	"sysafari.com/customs/tguard/oss"
+	"sysafari.com/customs/tguard/utils"
+)
+
+// PodDirName The folder of the local copies of POD files, next to the ICP file.
+// Every ICP file has its own sub folder, so the POD files of different ICP files never share a path.
+const PodDirName = "pod"
+
+// isHttpUri Whether the uri is an HTTP link, otherwise it is an OSS object key
+func isHttpUri(uri string) bool {
+	return strings.HasPrefix(uri, "http://") || strings.HasPrefix(uri, "https://")
+}
+
+// documentName The file name of the document in the uri, exp: https://example.com/pod/123.pdf -> 123.pdf
+func documentName(uri string) string {
+	p := uri
+	if u, err := url.Parse(uri); err == nil && u.Path != "" {
+		p = u.Path
+	}
+	name := path.Base(strings.TrimRight(p, "/"))
+	if name == "." || name == "/" {
+		return ""
+	}
+	return name
+}
+
+// fetchDocument Fetch the document by HTTP or from the object store and save it to the path,
+// the file is checked by the Validate of the downloader whichever it is fetched from
+func fetchDocument(downloader *utils.Downloader, store oss.ObjectStore, uri string, key string, kind string, savePath string) error {
+	if uri == "" {
+		return errors.New("the document uri is empty")
+	}
+	if isHttpUri(uri) {
+		result := downloader.DownloadOne(utils.DownloadTask{
+			Key:      key,
+			Kind:     kind,
+			Uri:      uri,
+			SavePath: savePath,
+		})
+		if result.Failed() {
+			return errors.New(result.Error)
+		}
+		return nil
+	}
+	if store == nil {
+		return errors.New("the object store is not available")
+	}
+	if err := store.GetToFile(strings.TrimPrefix(uri, "/"), savePath); err != nil {
+		return err
+	}
+	if downloader.Validate != nil {
+		if err := downloader.Validate(savePath); err != nil {
+			_ = os.Remove(savePath)
+			return &utils.ValidationError{Uri: uri, Err: err}
+		}
+	}
+	return nil
+}
+
+// documentJob The document to fetch and where to save it
+type documentJob struct {
+	Key      string
+	Kind     string
+	Uri      string
+	SavePath string
+	// VerifyPDF Whether the document must be a valid PDF, exp: the tax receipts saved as .pdf
+	VerifyPDF bool
+	// Error The error of fetching, empty if success
+	Error string
+}
+
+// fetchDocuments Fetch the documents with a bounded worker pool, the errors are recorded in the jobs
+func fetchDocuments(jobs []*documentJob) {
+	downloader := newDocumentDownloader(false)
+	pdfDownloader := newDocumentDownloader(true)
+	// 对象存储不可用时，只有 OSS 的文件失败，HTTP 链接照常下载
+	store, err := oss.NewStore()
+	if err != nil {
+		log.Printf("Create object store failed: %v\n", err)
+	}
+
+	workers := downloader.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	ch := make(chan *documentJob)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for job := range ch {
+				d := downloader
+				if job.VerifyPDF {
+					d = pdfDownloader
+				}
+				if err := fetchDocument(d, store, job.Uri, job.Key, job.Kind, job.SavePath); err != nil {
+					job.Error = err.Error()
+				}
+			}
+		}()
+	}
+	for _, job := range jobs {
+		ch <- job
+	}
+	close(ch)
+	wg.Wait()
+}
+
+// newDocumentDownloader Create the downloader of the supporting documents with the zip.download-* settings,
+// the content type is not restricted since the documents are not only PDF.
+// The PDF documents are verified like the vat notes, so an error page is not saved as a PDF.
+func newDocumentDownloader(verifyPDF bool) *utils.Downloader {
+	d := newVatNoteDownloader()
+	d.ContentTypes = nil
+	if !verifyPDF {
+		d.Validate = nil
+	}
+	return d
+}
+
+// fetchPodFiles Fetch the POD files into the folder(relative to the base directory, exp: pod/BE0796544895_202209_01154020),
+// the files of the same link are fetched only once and the existing files are not fetched again.
+// The relative path of the local copy is set to the POD data, returns the documents fetched.
+func fetchPodFiles(pods []PodFileObject, baseDir string, relDir string) []PackageDocument {
+	podDir := filepath.Join(baseDir, filepath.FromSlash(relDir))
+	if !utils.IsExists(podDir) && !utils.CreateDir(podDir) {
+		fmt.Printf("Create POD dir: %s failed.\n", podDir)
+	}
+
+	podPaths := map[string]string{}
+	usedNames := map[string]string{}
+	var jobs []*documentJob
+	var docs []PackageDocument
+	for _, pod := range pods {
+		uri := pod.PodFileLink.String
+		if uri == "" || podPaths[uri] != "" {
+			continue
+		}
+		name := documentName(uri)
+		if other, ok := usedNames[name]; name == "" || (ok && other != uri) {
+			name = pod.TrackingNo + "_" + name
+		}
+		usedNames[name] = uri
+		rel := filepath.ToSlash(filepath.Join(relDir, name))
+		podPaths[uri] = rel
+
+		docs = append(docs, PackageDocument{Kind: DocumentKindPod, CustomsId: pod.CustomsId, Mrn: pod.Mrn, Source: uri, Path: rel})
+		savePath := filepath.Join(baseDir, rel)
+		if utils.IsExists(savePath) {
+			continue
+		}
+		jobs = append(jobs, &documentJob{Key: pod.CustomsId, Kind: DocumentKindPod, Uri: uri, SavePath: savePath})
+	}
+
+	log.Printf("Fetching %d POD files into %s \n", len(jobs), podDir)
+	fetchDocuments(jobs)
+
+	failed := map[string]string{}
+	for _, job := range jobs {
+		if job.Error != "" {
+			failed[job.Uri] = job.Error
+		}
+	}
+	for i := range docs {
+		if e, ok := failed[docs[i].Source]; ok {
+			docs[i].Error, docs[i].Path = e, ""
+		}
+	}
+	for i := range pods {
+		uri := pods[i].PodFileLink.String
+		if uri == "" {
+			continue
+		}
+		if e, ok := failed[uri]; ok {
+			pods[i].FetchError = e
+			continue
+		}
+		pods[i].LocalPath = podPaths[uri]
+	}
+	return docs
+}
+
+// embedPodFiles Fetch the POD files next to the ICP file, the POD sheet links to the local copies.
+// The files are saved in the sub folder of the ICP file, exp: pod/BE0796544895_202209_01154020/
+func embedPodFiles(pods []PodFileObject, icpFilePath string) {
+	relDir := path.Join(PodDirName, strings.TrimSuffix(filepath.Base(icpFilePath), filepath.Ext(icpFilePath)))
+	docs := fetchPodFiles(pods, filepath.Dir(icpFilePath), relDir)
+	missing := 0
+	for _, doc := range docs {
+		if doc.Error != "" {
+			missing++
+		}
+	}
+	log.Printf("Embedded %d POD files of ICP %s, %d missing \n", len(docs)-missing, filepath.Base(icpFilePath), missing)
+}
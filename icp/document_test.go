@@ -0,0 +1,48 @@
+package icp
+
+import (
+	"database/sql"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestEmbedPodFilesKeepsEveryICPApart(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write([]byte(r.URL.Path))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	// 两个ICP的POD文件同名但链接不同
+	first := []PodFileObject{{CustomsId: "C1", TrackingNo: "T1", PodFileLink: sql.NullString{String: srv.URL + "/a/pod.pdf", Valid: true}}}
+	second := []PodFileObject{{CustomsId: "C2", TrackingNo: "T2", PodFileLink: sql.NullString{String: srv.URL + "/b/pod.pdf", Valid: true}}}
+	embedPodFiles(first, filepath.Join(dir, "BE01_202209_01154020.xlsx"))
+	embedPodFiles(second, filepath.Join(dir, "BE02_202209_01154020.xlsx"))
+
+	tests := []struct {
+		pod      PodFileObject
+		path     string
+		contents string
+	}{
+		{first[0], "pod/BE01_202209_01154020/pod.pdf", "/a/pod.pdf"},
+		{second[0], "pod/BE02_202209_01154020/pod.pdf", "/b/pod.pdf"},
+	}
+	for _, tt := range tests {
+		if tt.pod.FetchError != "" {
+			t.Fatalf("fetch %s failed: %s", tt.pod.PodFileLink.String, tt.pod.FetchError)
+		}
+		if tt.pod.LocalPath != tt.path {
+			t.Errorf("local path = %q, want %q", tt.pod.LocalPath, tt.path)
+		}
+		b, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(tt.path)))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(b) != tt.contents {
+			t.Errorf("%s = %q, want %q", tt.path, b, tt.contents)
+		}
+	}
+}
@@ -1,14 +1,19 @@
 package icp
 
 import (
+	"compress/flate"
+	"encoding/json"
 	"fmt"
 	"github.com/spf13/viper"
 	"github.com/xuri/excelize/v2"
 	"log"
+	"os"
 	"path/filepath"
 	"strings"
+	"sysafari.com/customs/tguard/archive"
 	"sysafari.com/customs/tguard/global"
 	"sysafari.com/customs/tguard/icp/script"
+	"sysafari.com/customs/tguard/oss"
 	"sysafari.com/customs/tguard/utils"
 	"time"
 )
@@ -34,16 +39,34 @@ type FileOfICP struct {
 	TaxFileData []TaxFileObject `json:"tax_file_data"`
 	// PodFileData The data used to fill the pod file table
 	PodFileData []PodFileObject `json:"pod_file_data"`
+	// Summary The tax totals reconciliation of the tax data
+	Summary *ICPSummary `json:"summary"`
+	// RateSource Where the exchange rates used to convert the declared values come from
+	RateSource string `json:"rate_source"`
 	// FilePath The full path of ICP file.
 	FilePath string `json:"file_path"`
 	// FileName The ICP file name
 	FileName string `json:"file_name"`
+	// PreviousFileName The ICP file which this file is a new version of
+	PreviousFileName string `json:"previous_file_name"`
+	// RemovedCustomsIDs The customs IDs removed from the previous version of the ICP file
+	RemovedCustomsIDs []string `json:"removed_customs_ids"`
+	// AddedCustomsIDs The customs IDs newly appended to the previous version of the ICP file
+	AddedCustomsIDs []string `json:"added_customs_ids"`
+	// ExistingCustomsIDs The customs IDs requested to append but already in the previous version of the ICP file
+	ExistingCustomsIDs []string `json:"existing_customs_ids"`
 	// VatNoteZipFileName
 	VatNoteZipFileName string `json:"vat_noes_zip_file_name"`
 	// VatNoteZipFilePath
 	VatNoteZipFilePath string `json:"vat_noes_zip_file_path"`
-	// VatNoteDownloadDir
+	// VatNoteManifest The download results of the vat note files
+	VatNoteManifest *VatNoteManifest `json:"vat_note_manifest"`
+	// VatNoteDownloadDir The temporary work directory of this run, removed after the zip is made
 	VatNoteDownloadDir string `json:"vat_note_download_dir"`
+	// OssKey The object key of the uploaded ICP file, empty if not uploaded
+	OssKey string `json:"oss_key"`
+	// VatNoteOssKey The object key of the uploaded vat-note zip, empty if not uploaded
+	VatNoteOssKey string `json:"vat_note_oss_key"`
 	// Errors The ICP errors
 	Errors []string `json:"errors"`
 }
@@ -63,7 +86,7 @@ func (f *FileOfICP) QueryCustomsIDs() {
 	var customsIds []string
 	//err := global.Db.Select(&customsIds, script.QueryCustomsIdForICPWithinOneMonthSql, f.DutyParty, f.Month)
 	// 区分拆分报关单，after: 2024-08-29
-	err := global.Db.Select(&customsIds, script.QueryCustomsByDutyPartyForMonthAfterSplitSql, f.DutyParty, f.Month)
+	err := global.Select(global.ReadDb, &customsIds, script.QueryCustomsByDutyPartyForMonthAfterSplitSql, f.DutyParty, f.Month)
 	if err != nil || len(customsIds) == 0 {
 		f.Errors = append(f.Errors, fmt.Sprintf("Can not query customs for duty party %s with month %s", f.DutyParty, f.Month))
 	}
@@ -96,26 +119,72 @@ func (f *FileOfICP) readyForVatNote() {
 		f.Errors = append(f.Errors, fmt.Sprintf("Create vat note zip save dir: %s, failed.", vatNoteDir))
 	}
 
-	vatNoteDownloadDir := filepath.Join(vatNoteDir, monthDate.Format("2006-01"))
-	fmt.Println("Vat note download dir: ", vatNoteDownloadDir)
-	if utils.IsExists(vatNoteDownloadDir) {
-		// 清空路径下所有文件
-		if !utils.Clear(vatNoteDownloadDir) {
-			f.Errors = append(f.Errors, fmt.Sprintf("Clear vat note download dir: %s, failed.", vatNoteDownloadDir))
-		}
-	} else {
-		if !utils.CreateDir(vatNoteDownloadDir) {
-			f.Errors = append(f.Errors, fmt.Sprintf("Create vat note download dir: %s, failed.", vatNoteDownloadDir))
-		}
+	// 每次生成使用独立的工作目录，避免不同税代或同一税代多次生成时相互清空或混入文件
+	workRoot := filepath.Join(vatNoteDir, ".work")
+	if !utils.IsExists(workRoot) && !utils.CreateDir(workRoot) {
+		f.Errors = append(f.Errors, fmt.Sprintf("Create vat note work dir: %s, failed.", workRoot))
+		return
+	}
+	runId := time.Now().Format("20060102150405")
+	vatNoteDownloadDir, err := os.MkdirTemp(workRoot, fmt.Sprintf("%s-%s-%s-", monthDate.Format("2006-01"), f.DutyParty, runId))
+	if err != nil {
+		f.Errors = append(f.Errors, fmt.Sprintf("Create vat note download dir in %s, failed: %v", workRoot, err))
+		return
 	}
+	fmt.Println("Vat note download dir: ", vatNoteDownloadDir)
 
 	f.VatNoteZipFileName = vatNoteZipFileName
 	f.VatNoteZipFilePath = filepath.Join(vatNoteDir, vatNoteZipFileName)
 	f.VatNoteDownloadDir = vatNoteDownloadDir
 }
 
-// downloadVatNoteAndMakeZip Download vat note file of customs and compress them to zip
-func downloadVatNoteAndMakeZip(customsIds []string, downloadDir string, zipFileName string) {
+// VatNoteFailure The vat note or transfer doc of the customs failed to download
+type VatNoteFailure struct {
+	CustomsId string `json:"customs_id"`
+	FileType  string `json:"file_type"`
+	Uri       string `json:"uri"`
+	Attempts  int    `json:"attempts"`
+	Error     string `json:"error"`
+}
+
+// VatNoteManifest The download results of the vat-note zip, saved as manifest.json in the zip
+type VatNoteManifest struct {
+	GeneratedAt  string `json:"generated_at"`
+	CustomsTotal int    `json:"customs_total"`
+	FileTotal    int    `json:"file_total"`
+	FailedTotal  int    `json:"failed_total"`
+	// MissingCustoms The customs which have any document failed to download or verify
+	MissingCustoms []string         `json:"missing_customs"`
+	MissingRatio   float64          `json:"missing_ratio"`
+	Failures       []VatNoteFailure `json:"failures"`
+}
+
+// newVatNoteDownloader Create the downloader of vat note with the zip.download-* settings
+func newVatNoteDownloader() *utils.Downloader {
+	viper.SetDefault("zip.download-workers", 4)
+	viper.SetDefault("zip.download-timeout", 60)
+	viper.SetDefault("zip.download-retries", 3)
+	viper.SetDefault("zip.download-backoff", 1)
+	viper.SetDefault("zip.download-content-types", []string{"application/pdf", "application/octet-stream"})
+	viper.SetDefault("zip.vat-note-min-size", 1024)
+	minSize := viper.GetInt64("zip.vat-note-min-size")
+
+	return &utils.Downloader{
+		Workers:      viper.GetInt("zip.download-workers"),
+		Timeout:      time.Duration(viper.GetInt("zip.download-timeout")) * time.Second,
+		Retries:      viper.GetInt("zip.download-retries"),
+		Backoff:      time.Duration(viper.GetInt("zip.download-backoff")) * time.Second,
+		ContentTypes: viper.GetStringSlice("zip.download-content-types"),
+		Validate: func(path string) error {
+			_, err := utils.VerifyPDF(path, minSize)
+			return err
+		},
+	}
+}
+
+// vatNoteTasks The download tasks of the vat note and transfer doc of every customs,
+// the files are saved into vat-note and transfer-doc under the download directory
+func vatNoteTasks(customsIds []string, downloadDir string) []utils.DownloadTask {
 	vatNoteUri := viper.GetString("zip.vat-note-download-uri")
 	vatNoteDir := filepath.Join(downloadDir, "vat-note")
 	utils.CreateDir(vatNoteDir)
@@ -123,43 +192,137 @@ func downloadVatNoteAndMakeZip(customsIds []string, downloadDir string, zipFileN
 	transferDocDir := filepath.Join(downloadDir, "transfer-doc")
 	utils.CreateDir(transferDocDir)
 
-	for i, d := range customsIds {
-		fmt.Printf("Downloading vat note idx: %d ,customsId:%s \n", i, d)
+	var tasks []utils.DownloadTask
+	for _, d := range customsIds {
 		uri := strings.ReplaceAll(vatNoteUri, "CUSTOMS_ID", d)
+		tasks = append(tasks, utils.DownloadTask{
+			Key:      d,
+			Kind:     "vatNote",
+			Uri:      strings.ReplaceAll(uri, "FILE_TYPE", "vatNote"),
+			SavePath: filepath.Join(vatNoteDir, d+"_vat_note.pdf"),
+		}, utils.DownloadTask{
+			Key:      d,
+			Kind:     "transferDoc",
+			Uri:      strings.ReplaceAll(uri, "FILE_TYPE", "transferDoc"),
+			SavePath: filepath.Join(transferDocDir, d+"_transfer_doc.pdf"),
+		})
+	}
+	return tasks
+}
 
-		vatNoteUri := strings.ReplaceAll(uri, "FILE_TYPE", "vatNote")
-		vatNotDownloadFile := filepath.Join(vatNoteDir, d+"_vat_note.pdf")
-
-		fmt.Printf("Downloading vat note uri: %s, save to: %s \n", vatNoteUri, vatNotDownloadFile)
-		err := utils.DownloadFileTo(vatNoteUri, vatNotDownloadFile)
-		if err != nil {
-			fmt.Printf("Download vat note file failed, uri: %s, err:%v \n", vatNoteUri, err)
+// downloadVatNoteAndMakeZip Download vat note file of customs and compress them to zip
+func downloadVatNoteAndMakeZip(customsIds []string, downloadDir string, zipFileName string) (*VatNoteManifest, error) {
+	tasks := vatNoteTasks(customsIds, downloadDir)
+	fmt.Printf("Downloading %d vat note and transfer doc files of %d customs \n", len(tasks), len(customsIds))
+	results := newVatNoteDownloader().Download(tasks)
+
+	manifest := &VatNoteManifest{
+		GeneratedAt:  time.Now().UTC().Format(time.RFC3339),
+		CustomsTotal: len(customsIds),
+		FileTotal:    len(tasks),
+	}
+	for _, r := range results {
+		if r.Failed() {
+			fmt.Printf("Download %s file failed, uri: %s, err:%v \n", r.Kind, r.Uri, r.Error)
+			manifest.Failures = append(manifest.Failures, VatNoteFailure{
+				CustomsId: r.Key,
+				FileType:  r.Kind,
+				Uri:       r.Uri,
+				Attempts:  r.Attempts,
+				Error:     r.Error,
+			})
 		}
-
-		transferDocUri := strings.ReplaceAll(uri, "FILE_TYPE", "transferDoc")
-		transferDownloadFile := filepath.Join(transferDocDir, d+"_transfer_doc.pdf")
-		fmt.Printf("Downloading transfer doc uri: %s, save to: %s \n", transferDocUri, transferDownloadFile)
-		err = utils.DownloadFileTo(transferDocUri, transferDownloadFile)
-		if err != nil {
-			fmt.Printf("Download transfer doc file failed, uri: %s, err:%v \n", transferDocUri, err)
+	}
+	manifest.FailedTotal = len(manifest.Failures)
+	for _, failure := range manifest.Failures {
+		if !utils.In(failure.CustomsId, manifest.MissingCustoms) {
+			manifest.MissingCustoms = append(manifest.MissingCustoms, failure.CustomsId)
 		}
 	}
+	if len(customsIds) > 0 {
+		manifest.MissingRatio = float64(len(manifest.MissingCustoms)) / float64(len(customsIds))
+	}
 
-	err := utils.Zip(downloadDir, zipFileName)
+	manifestJson, err := json.MarshalIndent(manifest, "", "  ")
 	if err != nil {
-		fmt.Printf("ZipCompose failed,err:%v \n", err)
+		return manifest, err
 	}
+	err = os.WriteFile(filepath.Join(downloadDir, "manifest.json"), manifestJson, 0644)
+	if err != nil {
+		return manifest, err
+	}
+
+	// 缺失文件的报关单比例超过阈值时，不生成压缩包
+	maxMissingRatio := viper.GetFloat64("zip.vat-note-max-missing-ratio")
+	if maxMissingRatio > 0 && manifest.MissingRatio > maxMissingRatio {
+		return manifest, fmt.Errorf("%d of %d customs miss vat-note documents, the ratio %.2f exceeds %.2f",
+			len(manifest.MissingCustoms), len(customsIds), manifest.MissingRatio, maxMissingRatio)
+	}
+
+	// 先压缩到临时文件，完成后再原子地重命名为最终文件，避免下载方读到不完整的压缩包
+	tmpZip, err := os.CreateTemp(filepath.Dir(zipFileName), filepath.Base(zipFileName)+".*.tmp")
+	if err != nil {
+		return manifest, err
+	}
+	_ = tmpZip.Close()
+	defer os.Remove(tmpZip.Name())
+
+	viper.SetDefault("zip.deflate-level", flate.DefaultCompression)
+	err = archive.ZipDir(downloadDir, tmpZip.Name(), archive.WithLevel(viper.GetInt("zip.deflate-level")))
+	if err != nil {
+		fmt.Printf("Zip vat note files failed,err:%v \n", err)
+		return manifest, err
+	}
+	return manifest, os.Rename(tmpZip.Name(), zipFileName)
 }
 
-// GenerateVatNotesZip Download vat note file of customs and then make compression package
-func (f *FileOfICP) GenerateVatNotesZip() {
+// GenerateVatNotesZip Download vat note file of customs and then make compression package.
+// The vat note zip file name is cleared if the zip is not made.
+func (f *FileOfICP) GenerateVatNotesZip() error {
 	f.readyForVatNote()
 
 	if len(f.Errors) > 0 {
 		fmt.Printf("There has error: %s, cant make vat-note zip.\n", f.Errors)
-	} else {
-		fmt.Println("Will synchronize production vat-note zip.")
-		downloadVatNoteAndMakeZip(f.CustomsIDs, f.VatNoteDownloadDir, f.VatNoteZipFilePath)
+		f.VatNoteZipFileName = ""
+		return fmt.Errorf("ready for vat-note zip failed: %v", f.Errors)
+	}
+
+	// 无论成功与否，清理本次的工作目录
+	defer func() {
+		if err := os.RemoveAll(f.VatNoteDownloadDir); err != nil {
+			fmt.Printf("Remove vat note download dir: %s failed: %v \n", f.VatNoteDownloadDir, err)
+		}
+	}()
+
+	fmt.Println("Will synchronize production vat-note zip.")
+	manifest, err := downloadVatNoteAndMakeZip(f.CustomsIDs, f.VatNoteDownloadDir, f.VatNoteZipFilePath)
+	f.VatNoteManifest = manifest
+	if err != nil {
+		fmt.Printf("Make vat-note zip: %s failed: %v \n", f.VatNoteZipFilePath, err)
+		f.VatNoteZipFileName = ""
+		return err
+	}
+	if manifest.FailedTotal > 0 {
+		fmt.Printf("There are %d of %d vat-note files failed to download, see manifest.json in %s \n", manifest.FailedTotal, manifest.FileTotal, f.VatNoteZipFileName)
+	}
+	f.VatNoteOssKey = uploadArtifact(f.VatNoteZipFilePath, f.DutyParty, f.Month)
+	return nil
+}
+
+// saveVatNoteIntoDB Record the vat note zip on the newest ICP of the duty party in the month
+func (f *FileOfICP) saveVatNoteIntoDB() {
+	dt, err := time.Parse("2006-01", f.Month)
+	if err != nil {
+		f.Errors = append(f.Errors, fmt.Sprintf("ICP's month format error, %s.", f.Month))
+		return
+	}
+	res, err := global.Db.Exec(script.UpdateIcpVatNoteSql, f.VatNoteZipFileName, f.VatNoteOssKey, f.DutyParty, dt.Year(), int(dt.Month()))
+	if err != nil {
+		f.Errors = append(f.Errors, fmt.Sprintf("Save vat note(%s) of ICP failed: %v", f.VatNoteZipFileName, err))
+		return
+	}
+	if n, _ := res.RowsAffected(); n == 0 {
+		fmt.Printf("No ICP of duty party %s in the month %s, vat note %s not recorded.\n", f.DutyParty, f.Month, f.VatNoteZipFileName)
 	}
 }
 
@@ -176,15 +339,29 @@ func (f *FileOfICP) GenerateICP() string {
 		if len(f.Errors) > 0 {
 			log.Printf("Generating ICP query fill data error: %v \n", f.Errors)
 		}
+		rateSource, errs := convertToEUR(f.TaxData)
+		f.RateSource = rateSource
+		if len(errs) > 0 {
+			f.Errors = append(f.Errors, errs...)
+			log.Printf("Generating ICP convert currency error: %v \n", errs)
+		}
+
+		if viper.GetBool("icp.embed-pod") {
+			embedPodFiles(f.PodFileData, f.FilePath)
+		}
 
 		// 3. 生成ICP文件。将数据填充到excel文件中
 		f.createICPFile()
 		if len(f.Errors) > 0 {
 			log.Printf("Generating ICP create ICP excel file error: %v \n", f.Errors)
 		}
+		if utils.IsExists(f.FilePath) {
+			f.OssKey = uploadArtifact(f.FilePath, f.DutyParty, f.Month)
+		}
 
 		// 4. 保存ICP信息到数据库
 		f.saveICPInfoIntoDB(true)
+		f.saveSummaryIntoDB()
 		f.saveCustomsInfoWithinICP()
 		if len(f.Errors) > 0 {
 			log.Printf("Save ICP and customs info failed, error: %v \n", f.Errors)
@@ -195,6 +372,24 @@ func (f *FileOfICP) GenerateICP() string {
 	return ""
 }
 
+// uploadArtifact Upload the generated file to the object store if oss.upload is on,
+// the file is still usable locally if it failed, returns the object key
+func uploadArtifact(localPath string, dutyParty string, month string) string {
+	monthDt, err := time.Parse("2006-01", month)
+	if err != nil {
+		monthDt = time.Now()
+	}
+	key, err := oss.UploadArtifact(localPath, dutyParty, monthDt)
+	if err != nil {
+		log.Printf("Upload %s failed: %v \n", localPath, err)
+		return ""
+	}
+	if key != "" {
+		log.Printf("Uploaded %s as %s \n", localPath, key)
+	}
+	return key
+}
+
 // updateDutyPartyICPStatusForExist 更新同一个dutyParty,同一个月份的ICP文件为非最新
 func updateDutyPartyICPStatusForExist(dutyParty string, year, month int) {
 	var icpTotal int
@@ -219,15 +414,19 @@ func (f *FileOfICP) saveICPInfoIntoDB(status bool) {
 		f.Errors = append(f.Errors, fmt.Sprintf("ICP's filename(%s) error: %v", f.FileName, err))
 	}
 	serviceIcp := &ServiceICP{
-		DutyParty: f.DutyParty,
-		Name:      f.FileName,
-		Year:      dt.Year(),
-		Month:     int(dt.Month()),
-		IcpDate:   time.Now().UTC().Format("2006-01-02 15:04:05"),
-		Total:     len(f.CustomsIDs),
-		Status:    status,
-		VatNote:   f.VatNoteZipFileName,
-		IsNewest:  true,
+		DutyParty:     f.DutyParty,
+		Name:          f.FileName,
+		PreviousName:  f.PreviousFileName,
+		Year:          dt.Year(),
+		Month:         int(dt.Month()),
+		IcpDate:       time.Now().UTC().Format("2006-01-02 15:04:05"),
+		Total:         len(f.CustomsIDs),
+		Status:        status,
+		RateSource:    f.RateSource,
+		VatNote:       f.VatNoteZipFileName,
+		IsNewest:      true,
+		OssKey:        f.OssKey,
+		VatNoteOssKey: f.VatNoteOssKey,
 	}
 
 	// 更新同一个dutyParty,同一个月份的ICP文件为非最新
@@ -240,10 +439,27 @@ func (f *FileOfICP) saveICPInfoIntoDB(status bool) {
 	}
 }
 
+// saveSummaryIntoDB Save the tax totals reconciliation of ICP to database
+func (f *FileOfICP) saveSummaryIntoDB() {
+	if f.Summary == nil {
+		return
+	}
+	rows := f.Summary.Rows()
+	for i := range rows {
+		rows[i].IcpName = f.FileName
+	}
+
+	_, err := global.Db.NamedExec(script.InsertServiceICPSummary, rows)
+	if err != nil {
+		f.Errors = append(f.Errors, fmt.Sprintf("Save ICP(%s) summary failed: %v", f.FileName, err))
+	}
+}
+
 // saveCustomsInfoWithinICP Save relations information for customs and ICP
 func (f *FileOfICP) saveCustomsInfoWithinICP() {
 	var customsICPs []ServiceICPCustoms
 
+	withTaxFile := map[string]bool{}
 	for _, i2 := range f.TaxFileData {
 		customsId := i2.CustomsId
 		ci := ServiceICPCustoms{
@@ -253,8 +469,33 @@ func (f *FileOfICP) saveCustomsInfoWithinICP() {
 			InExcel:   utils.In(customsId, f.CustomsIDs),
 		}
 		customsICPs = append(customsICPs, ci)
+		withTaxFile[customsId] = true
+	}
+
+	// 没有税单文件的customs（如未配置税单链接的申报国）也要记录，否则下一个版本会丢失这些customs
+	for _, customsId := range f.CustomsIDs {
+		if withTaxFile[customsId] {
+			continue
+		}
+		customsICPs = append(customsICPs, ServiceICPCustoms{
+			IcpName:   f.FileName,
+			CustomsId: customsId,
+			InExcel:   true,
+		})
 	}
 
+	// 从上一版本中移除的customs，记录为不在Excel中
+	for _, customsId := range f.RemovedCustomsIDs {
+		customsICPs = append(customsICPs, ServiceICPCustoms{
+			IcpName:   f.FileName,
+			CustomsId: customsId,
+			InExcel:   false,
+		})
+	}
+
+	if len(customsICPs) == 0 {
+		return
+	}
 	_, err := global.Db.NamedExec(script.InsertServiceICPCustoms, customsICPs)
 	if err != nil {
 		f.Errors = append(f.Errors, fmt.Sprintf("Save ICP(%s) and customs information failed: %v", f.FileName, err))
@@ -291,26 +532,28 @@ func (f *FileOfICP) readyICPFileInfo() {
 		log.Panic("ICP root save directory not set ..")
 	}
 
-	monthDt, err := time.Parse("2006-01", f.Month)
-	if err != nil {
-		f.Errors = append(f.Errors, fmt.Sprintf("ICP's month format error, %s.", f.Month))
-	}
+	var monthDt time.Time
 	if f.FileName == "" {
 		if f.DutyParty == "" {
 			f.Errors = append(f.Errors, fmt.Sprintf("Duty party is required to generate ICP file, but is empty."))
 			return
 		}
+		d, err := time.Parse("2006-01", f.Month)
+		if err != nil {
+			f.Errors = append(f.Errors, fmt.Sprintf("ICP's month format error, %s.", f.Month))
+			return
+		}
+		monthDt = d
 		date, t := monthDt.Format(FileNameDateLayout), time.Now().Format(FileNameTimeLayout)
 		f.FileName = fmt.Sprintf("%s_%s_%s.xlsx", f.DutyParty, date, t)
 	} else {
-		fp := strings.Split(f.FileName, "_")
-		f.DutyParty = fp[0]
-		dt := fp[1]
-		d, err := time.Parse(FileNameDateLayout, dt)
+		dutyParty, d, err := parseICPFileName(f.FileName)
 		if err != nil {
-			f.Errors = append(f.Errors, fmt.Sprintf("The ICP filename:%s invalid format(correct: BE0796544895_200601_02150405.xlsx)", f.FileName))
+			f.Errors = append(f.Errors, err.Error())
 			return
 		}
+		// 通过文件名生成ICP时，税代和月份以文件名为准
+		f.DutyParty, f.Month = dutyParty, d.Format("2006-01")
 		monthDt = d
 	}
 
@@ -327,6 +570,16 @@ func (f *FileOfICP) readyICPFileInfo() {
 
 // createICPFile creates a ICP excel file
 func (f *FileOfICP) createICPFile() {
+	file := f.newICPWorkbook()
+
+	log.Printf("**** Save ICP excel: %s ****\n", f.FilePath)
+	if err := file.SaveAs(f.FilePath); err != nil {
+		f.Errors = append(f.Errors, fmt.Sprintf("Save ICP file on disk failed: %v", err))
+	}
+}
+
+// newICPWorkbook Fill the ICP data into a new workbook
+func (f *FileOfICP) newICPWorkbook() *excelize.File {
 	log.Println("**** Creating ICP excel ****")
 	file := excelize.NewFile()
 	icpDate := time.Now().Format(FileNameDateLayout)
@@ -349,8 +602,12 @@ func (f *FileOfICP) createICPFile() {
 		f.Errors = append(f.Errors, fmt.Sprintf("Fill POD sheet failed: %v", err))
 	}
 
-	log.Printf("**** Save ICP excel: %s ****\n", f.FilePath)
-	if err := file.SaveAs(f.FilePath); err != nil {
-		f.Errors = append(f.Errors, fmt.Sprintf("Save ICP file on disk failed: %v", err))
+	f.Summary = SummarizeTaxData(f.TaxData)
+	summarySheetName := fmt.Sprintf("%s_%s_%s", "SUMMARY", f.DutyParty, icpDate)
+	err = FillSummarySheet(file, summarySheetName, f.Summary)
+	if err != nil {
+		f.Errors = append(f.Errors, fmt.Sprintf("Fill summary sheet failed: %v", err))
 	}
+
+	return file
 }
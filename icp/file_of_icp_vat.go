@@ -23,10 +23,14 @@ type FileOfICPForVAT struct {
 	TaxFileData []TaxFileObject `json:"tax_file_data"`
 	// PodFileData The data used to fill the pod file table
 	PodFileData []PodFileObject `json:"pod_file_data"`
+	// RateSource Where the exchange rates used to convert the declared values come from
+	RateSource string `json:"rate_source"`
 	// FilePath The full path of ICP file.
 	FilePath string `json:"file_path"`
 	// FileName The ICP file name
 	FileName string `json:"file_name"`
+	// OssKey The object key of the uploaded ICP file, empty if not uploaded
+	OssKey string `json:"oss_key"`
 	// Errors The ICP errors
 	Errors []string `json:"errors"`
 }
@@ -56,11 +60,23 @@ func (f *FileOfICPForVAT) GenerateICP() string {
 		if len(f.Errors) > 0 {
 			log.Printf("Generating ICP query fill data error: %v \n", f.Errors)
 		}
+		rateSource, errs := convertToEUR(f.TaxData)
+		f.RateSource = rateSource
+		if len(errs) > 0 {
+			f.Errors = append(f.Errors, errs...)
+			log.Printf("Generating ICP convert currency error: %v \n", errs)
+		}
+		if viper.GetBool("icp.embed-pod") {
+			embedPodFiles(f.PodFileData, f.FilePath)
+		}
 
 		f.createICPFile()
 		if len(f.Errors) > 0 {
 			log.Printf("Generating ICP create ICP excel file error: %v \n", f.Errors)
 		}
+		if utils.IsExists(f.FilePath) {
+			f.OssKey = uploadArtifact(f.FilePath, f.VatNo, time.Now().Format("2006-01"))
+		}
 
 		f.saveICPInfoIntoDB(true)
 		// 不保存 ICP 与Customs 关系
@@ -179,13 +195,15 @@ func (f *FileOfICPForVAT) saveICPInfoIntoDB(status bool) {
 	dt := time.Now()
 
 	serviceIcp := &ServiceICP{
-		DutyParty: f.VatNo,
-		Name:      f.FileName,
-		Year:      dt.Year(),
-		Month:     int(dt.Month()),
-		IcpDate:   time.Now().UTC().Format("2006-01-02 15:04:05"),
-		Total:     len(f.CustomsIDs),
-		Status:    status,
+		DutyParty:  f.VatNo,
+		Name:       f.FileName,
+		Year:       dt.Year(),
+		Month:      int(dt.Month()),
+		IcpDate:    time.Now().UTC().Format("2006-01-02 15:04:05"),
+		Total:      len(f.CustomsIDs),
+		Status:     status,
+		RateSource: f.RateSource,
+		OssKey:     f.OssKey,
 	}
 	_, err := global.Db.NamedExec(script.InsertServiceICP, serviceIcp)
 	if err != nil {
@@ -5,11 +5,14 @@ import (
 	"github.com/xuri/excelize/v2"
 	"log"
 	"strings"
+	"sysafari.com/customs/tguard/decimal"
+	"sysafari.com/customs/tguard/workbook"
 )
 
 // FillTaxSheet fill tax sheet
 func FillTaxSheet(file *excelize.File, sheetName string, taxData []TaxObject) error {
 	log.Println("ICP sheet name: ", sheetName)
+	profile := workbook.LoadProfile()
 	file.SetSheetName("Sheet1", sheetName)
 
 	TaxSheetHeaders := &[]interface{}{"SN", "BIll NO.", "Tax Type", "Itemnr", "Destined Number", "Processing Status",
@@ -17,7 +20,8 @@ func FillTaxSheet(file *excelize.File, sheetName string, taxData []TaxObject) er
 		"Dutch Costs", "Ductch VAT", "Statistical Number", "Weight(KG)", "No. of Pieces", "Country Pre fix",
 		"VAT Registration Number", "Partner Name", "Country of Destination", "VAT Number", "EORI Number",
 		"Importer SS Code", "Address Code", "Address", "Postcode", "City", "Product No", "Description", "MRN",
-		"Company Name", "Mode", "HasInspectionFine", "ICP/115"}
+		"Company Name", "Mode", "HasInspectionFine", "ICP/115", "Original Currency", "Original Value",
+		"Exchange Rate", "EUR Value"}
 
 	err := file.SetSheetRow(sheetName, "A1", TaxSheetHeaders)
 	if err != nil {
@@ -37,12 +41,12 @@ func FillTaxSheet(file *excelize.File, sheetName string, taxData []TaxObject) er
 		err = file.SetCellStr(sheetName, fmt.Sprintf("H%d", idx), datum.InvoiceDate)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("I%d", idx), "")
 		err = file.SetCellStr(sheetName, fmt.Sprintf("J%d", idx), datum.Currency)
-		err = file.SetCellFloat(sheetName, fmt.Sprintf("K%d", idx), datum.LocalCurrencyValue, FloatDecimalPlaces, 64)
-		err = file.SetCellFloat(sheetName, fmt.Sprintf("L%d", idx), datum.ImportDuty, FloatDecimalPlaces, 64)
-		err = file.SetCellStr(sheetName, fmt.Sprintf("M%d", idx), datum.DutchCost)
-		err = file.SetCellStr(sheetName, fmt.Sprintf("N%d", idx), datum.DutchVat)
+		err = setCellDecimal(file, sheetName, fmt.Sprintf("K%d", idx), datum.LocalCurrencyValue)
+		err = setCellDecimal(file, sheetName, fmt.Sprintf("L%d", idx), datum.ImportDuty)
+		err = setCellText(file, sheetName, fmt.Sprintf("M%d", idx), datum.DutchCost)
+		err = setCellText(file, sheetName, fmt.Sprintf("N%d", idx), datum.DutchVat)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("O%d", idx), datum.HsCode.String)
-		err = file.SetCellFloat(sheetName, fmt.Sprintf("P%d", idx), datum.NetWeight, FloatDecimalPlaces, 64)
+		err = setCellDecimal(file, sheetName, fmt.Sprintf("P%d", idx), datum.NetWeight)
 		err = file.SetCellInt(sheetName, fmt.Sprintf("Q%d", idx), datum.Quantity)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("R%d", idx), datum.CountryPreFix)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("S%d", idx), datum.DutyParty.String)
@@ -62,18 +66,59 @@ func FillTaxSheet(file *excelize.File, sheetName string, taxData []TaxObject) er
 		err = file.SetCellStr(sheetName, fmt.Sprintf("AG%d", idx), datum.Mode)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("AH%d", idx), datum.HasInspectionFine)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("AI%d", idx), datum.InICPFile)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("AJ%d", idx), datum.OriginalCurrency)
+		err = setCellDecimal(file, sheetName, fmt.Sprintf("AK%d", idx), datum.OriginalAmount)
+		err = setCellDecimal(file, sheetName, fmt.Sprintf("AL%d", idx), datum.ExchangeRate)
+		err = setCellDecimal(file, sheetName, fmt.Sprintf("AM%d", idx), datum.EURValue)
 
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	last := len(taxData) + 1
+	// K-N: 金额, P: 重量, AK: 原始金额, AL: 汇率, AM: 欧元金额
+	err = profile.SetColumnsNumFmt(file, sheetName, "K", "N", last, profile.CurrencyNumFmt)
+	if err != nil {
+		return err
+	}
+	err = profile.SetColumnsNumFmt(file, sheetName, "P", "P", last, profile.WeightNumFmt)
+	if err != nil {
+		return err
+	}
+	err = profile.SetColumnsNumFmt(file, sheetName, "AK", "AK", last, profile.CurrencyNumFmt)
+	if err != nil {
+		return err
+	}
+	err = profile.SetColumnsNumFmt(file, sheetName, "AL", "AL", last, profile.RateNumFmt)
+	if err != nil {
+		return err
+	}
+	err = profile.SetColumnsNumFmt(file, sheetName, "AM", "AM", last, profile.CurrencyNumFmt)
+	if err != nil {
+		return err
+	}
+
+	return profile.ApplySheet(file, sheetName)
+}
+
+// setCellDecimal Set the decimal value into the cell as a number
+func setCellDecimal(file *excelize.File, sheetName, axis string, value decimal.Decimal) error {
+	return file.SetCellFloat(sheetName, axis, value.Float64(), FloatDecimalPlaces, 64)
+}
+
+// setCellText Set the text value into the cell, as a number if it is a decimal, otherwise as the placeholder string
+func setCellText(file *excelize.File, sheetName, axis string, value decimal.Text) error {
+	if value.IsPlaceholder() {
+		return file.SetCellStr(sheetName, axis, value.Placeholder)
+	}
+	return setCellDecimal(file, sheetName, axis, value.Decimal)
 }
 
 // FillTaxFileSheet fill tax file sheet
 func FillTaxFileSheet(file *excelize.File, sheetName string, taxFileData []TaxFileObject) error {
 	log.Println("Tax file sheet name: ", sheetName)
+	profile := workbook.LoadProfile()
 	file.NewSheet(sheetName)
 
 	TaxSheetHeaders := &[]interface{}{"SN", "MRN", "Tax receipt Link"}
@@ -88,19 +133,20 @@ func FillTaxFileSheet(file *excelize.File, sheetName string, taxFileData []TaxFi
 		idx := sn + 1
 		err = file.SetCellInt(sheetName, fmt.Sprintf("A%d", idx), sn)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("B%d", idx), datum.Mrn)
-		err = file.SetCellStr(sheetName, fmt.Sprintf("C%d", idx), datum.TaxFileLink)
+		err = profile.SetLink(file, sheetName, fmt.Sprintf("C%d", idx), datum.TaxFileLink)
 
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return profile.ApplySheet(file, sheetName)
 }
 
 // FillPodSheet fill pod file sheet
 func FillPodSheet(file *excelize.File, sheetName string, podFileData []PodFileObject) error {
 	log.Println("POD sheet name: ", sheetName)
+	profile := workbook.LoadProfile()
 	file.NewSheet(sheetName)
 
 	TaxSheetHeaders := &[]interface{}{"SN", "Bill No.", "Invoice Number", "MRN No.", "Tracing No.", "POD Filename", "POD Link", "Invoice"}
@@ -110,10 +156,21 @@ func FillPodSheet(file *excelize.File, sheetName string, podFileData []PodFileOb
 		fmt.Println(err)
 		return err
 	}
+	// 缺失POD的行标黄
+	missingStyle, err := file.NewStyle(&excelize.Style{
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"#FFEB9C"}, Pattern: 1},
+	})
+	if err != nil {
+		return err
+	}
 	for i, datum := range podFileData {
 		sn := i + 1
 		idx := sn + 1
+		// 有本地副本时链接指向ICP文件旁的pod目录
 		link := datum.PodFileLink.String
+		if datum.LocalPath != "" {
+			link = datum.LocalPath
+		}
 		podName := ""
 		if link != "" {
 			pt := strings.Split(link, "/")
@@ -126,12 +183,16 @@ func FillPodSheet(file *excelize.File, sheetName string, podFileData []PodFileOb
 		err = file.SetCellStr(sheetName, fmt.Sprintf("D%d", idx), datum.Mrn)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("E%d", idx), datum.TrackingNo)
 		err = file.SetCellStr(sheetName, fmt.Sprintf("F%d", idx), podName)
-		err = file.SetCellStr(sheetName, fmt.Sprintf("G%d", idx), link)
+		err = profile.SetLink(file, sheetName, fmt.Sprintf("G%d", idx), link)
+		if datum.Missing() {
+			err = file.SetCellStr(sheetName, fmt.Sprintf("H%d", idx), "POD MISSING "+datum.FetchError)
+			err = file.SetCellStyle(sheetName, fmt.Sprintf("A%d", idx), fmt.Sprintf("H%d", idx), missingStyle)
+		}
 
 		if err != nil {
 			return err
 		}
 	}
 
-	return nil
+	return profile.ApplySheet(file, sheetName)
 }
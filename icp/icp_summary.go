@@ -0,0 +1,148 @@
+package icp
+
+import (
+	"fmt"
+	"github.com/xuri/excelize/v2"
+	"log"
+	"sort"
+	"sysafari.com/customs/tguard/decimal"
+	"sysafari.com/customs/tguard/workbook"
+)
+
+const (
+	SummaryDimensionTotal       = "TOTAL"
+	SummaryDimensionTaxType     = "TAX_TYPE"
+	SummaryDimensionCountry     = "COUNTRY"
+	SummaryDimensionProcessCode = "PROCESS_CODE"
+	SummaryDimensionMode        = "MODE"
+)
+
+// SummaryTotal The totals of the tax data grouped by one dimension, sysafari.service_icp_summary
+type SummaryTotal struct {
+	IcpName            string          `db:"icp_name"`
+	Dimension          string          `db:"dimension"`
+	Key                string          `db:"dimension_key"`
+	CustomsTotal       int             `db:"customs_total"`
+	MrnTotal           int             `db:"mrn_total"`
+	ItemTotal          int             `db:"item_total"`
+	LocalCurrencyValue decimal.Decimal `db:"local_currency_value"`
+	ImportDuty         decimal.Decimal `db:"import_duty"`
+
+	customs map[string]bool
+	mrns    map[string]bool
+	items   map[string]bool
+}
+
+// add Add a row of tax data into the totals, the declared value is counted once per item
+func (st *SummaryTotal) add(t TaxObject) {
+	if st.customs == nil {
+		st.customs, st.mrns, st.items = map[string]bool{}, map[string]bool{}, map[string]bool{}
+	}
+	st.customs[t.CustomsId] = true
+	if t.Mrn != "" {
+		st.mrns[t.Mrn] = true
+	}
+	// 同一个商品的A00和B00两行申报价值相同，申报价值每个商品只计一次
+	item := t.CustomsId + "#" + t.ItemNumber
+	if !st.items[item] {
+		st.items[item] = true
+		st.LocalCurrencyValue = st.LocalCurrencyValue.Add(t.LocalCurrencyValue)
+	}
+	st.CustomsTotal, st.MrnTotal, st.ItemTotal = len(st.customs), len(st.mrns), len(st.items)
+
+	st.ImportDuty = st.ImportDuty.Add(t.ImportDuty)
+}
+
+// ICPSummary The reconciliation totals of an ICP file
+type ICPSummary struct {
+	Total         SummaryTotal
+	ByTaxType     []SummaryTotal
+	ByCountry     []SummaryTotal
+	ByProcessCode []SummaryTotal
+	ByMode        []SummaryTotal
+}
+
+// Rows All totals of the summary, the overall totals first
+func (s *ICPSummary) Rows() []SummaryTotal {
+	rows := []SummaryTotal{s.Total}
+	rows = append(rows, s.ByTaxType...)
+	rows = append(rows, s.ByCountry...)
+	rows = append(rows, s.ByProcessCode...)
+	rows = append(rows, s.ByMode...)
+	return rows
+}
+
+// groupTaxData Group the tax data by the key and sum every group, the groups are sorted by key
+func groupTaxData(dimension string, taxData []TaxObject, key func(t TaxObject) string) []SummaryTotal {
+	groups := map[string]*SummaryTotal{}
+	var keys []string
+	for _, t := range taxData {
+		k := key(t)
+		g, ok := groups[k]
+		if !ok {
+			g = &SummaryTotal{Dimension: dimension, Key: k}
+			groups[k] = g
+			keys = append(keys, k)
+		}
+		g.add(t)
+	}
+
+	sort.Strings(keys)
+	var totals []SummaryTotal
+	for _, k := range keys {
+		totals = append(totals, *groups[k])
+	}
+	return totals
+}
+
+// SummarizeTaxData Sum the tax data per tax type, destination country, process code and mode
+func SummarizeTaxData(taxData []TaxObject) *ICPSummary {
+	summary := &ICPSummary{
+		Total: SummaryTotal{Dimension: SummaryDimensionTotal, Key: "ALL"},
+	}
+	for _, t := range taxData {
+		summary.Total.add(t)
+	}
+	summary.ByTaxType = groupTaxData(SummaryDimensionTaxType, taxData, func(t TaxObject) string { return t.TaxType })
+	summary.ByCountry = groupTaxData(SummaryDimensionCountry, taxData, func(t TaxObject) string { return t.CountryOfDestination })
+	summary.ByProcessCode = groupTaxData(SummaryDimensionProcessCode, taxData, func(t TaxObject) string { return t.ProcessCode })
+	summary.ByMode = groupTaxData(SummaryDimensionMode, taxData, func(t TaxObject) string { return t.Mode })
+	return summary
+}
+
+// FillSummarySheet fill the tax totals reconciliation sheet
+func FillSummarySheet(file *excelize.File, sheetName string, summary *ICPSummary) error {
+	log.Println("Summary sheet name: ", sheetName)
+	profile := workbook.LoadProfile()
+	file.NewSheet(sheetName)
+
+	SummarySheetHeaders := &[]interface{}{"Dimension", "Key", "Customs", "MRN", "Items", "LocalCurrency Value", "Import Duty"}
+
+	err := file.SetSheetRow(sheetName, "A1", SummarySheetHeaders)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	rows := summary.Rows()
+	for i, datum := range rows {
+		idx := i + 2
+		err = file.SetCellStr(sheetName, fmt.Sprintf("A%d", idx), datum.Dimension)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("B%d", idx), datum.Key)
+		err = file.SetCellInt(sheetName, fmt.Sprintf("C%d", idx), datum.CustomsTotal)
+		err = file.SetCellInt(sheetName, fmt.Sprintf("D%d", idx), datum.MrnTotal)
+		err = file.SetCellInt(sheetName, fmt.Sprintf("E%d", idx), datum.ItemTotal)
+		err = setCellDecimal(file, sheetName, fmt.Sprintf("F%d", idx), datum.LocalCurrencyValue)
+		err = setCellDecimal(file, sheetName, fmt.Sprintf("G%d", idx), datum.ImportDuty)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	err = profile.SetColumnsNumFmt(file, sheetName, "F", "G", len(rows)+1, profile.CurrencyNumFmt)
+	if err != nil {
+		return err
+	}
+	return profile.ApplySheet(file, sheetName)
+}
@@ -0,0 +1,45 @@
+package icp
+
+import (
+	"sysafari.com/customs/tguard/decimal"
+	"testing"
+)
+
+func TestSummarizeTaxDataCountsDeclaredValueOncePerItem(t *testing.T) {
+	taxData := []TaxObject{
+		{CustomsId: "C1", Mrn: "M1", ItemNumber: "1", TaxType: "A00", CountryOfDestination: "NL", LocalCurrencyValue: decimal.NewFromInt(100), ImportDuty: decimal.NewFromInt(4)},
+		{CustomsId: "C1", Mrn: "M1", ItemNumber: "1", TaxType: "B00", CountryOfDestination: "NL", LocalCurrencyValue: decimal.NewFromInt(100), ImportDuty: decimal.NewFromInt(21)},
+		{CustomsId: "C1", Mrn: "M1", ItemNumber: "2", TaxType: "A00", CountryOfDestination: "NL", LocalCurrencyValue: decimal.NewFromInt(50), ImportDuty: decimal.NewFromInt(2)},
+		{CustomsId: "C1", Mrn: "M1", ItemNumber: "2", TaxType: "B00", CountryOfDestination: "NL", LocalCurrencyValue: decimal.NewFromInt(50), ImportDuty: decimal.NewFromInt(10)},
+		// 只有B00的商品也要计入申报价值
+		{CustomsId: "C2", Mrn: "M2", ItemNumber: "1", TaxType: "B00", CountryOfDestination: "BE", LocalCurrencyValue: decimal.NewFromInt(30), ImportDuty: decimal.NewFromInt(6)},
+	}
+
+	summary := SummarizeTaxData(taxData)
+
+	total := summary.Total
+	if total.LocalCurrencyValue != decimal.NewFromInt(180) {
+		t.Errorf("total declared value = %s, want 180.00", total.LocalCurrencyValue)
+	}
+	if total.ImportDuty != decimal.NewFromInt(43) {
+		t.Errorf("total import duty = %s, want 43.00", total.ImportDuty)
+	}
+	if total.CustomsTotal != 2 || total.MrnTotal != 2 || total.ItemTotal != 3 {
+		t.Errorf("total counts = %d/%d/%d, want 2/2/3", total.CustomsTotal, total.MrnTotal, total.ItemTotal)
+	}
+
+	want := map[string]decimal.Decimal{"A00": decimal.NewFromInt(150), "B00": decimal.NewFromInt(180)}
+	for _, st := range summary.ByTaxType {
+		if st.LocalCurrencyValue != want[st.Key] {
+			t.Errorf("declared value of tax type %s = %s, want %s", st.Key, st.LocalCurrencyValue, want[st.Key])
+		}
+	}
+
+	byCountry := map[string]decimal.Decimal{}
+	for _, st := range summary.ByCountry {
+		byCountry[st.Key] = st.LocalCurrencyValue
+	}
+	if byCountry["NL"] != decimal.NewFromInt(150) || byCountry["BE"] != decimal.NewFromInt(30) {
+		t.Errorf("declared value by country = %v, want NL 150.00 and BE 30.00", byCountry)
+	}
+}
@@ -0,0 +1,124 @@
+package icp
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/xuri/excelize/v2"
+	"path/filepath"
+	"strings"
+	"sysafari.com/customs/tguard/global"
+	"sysafari.com/customs/tguard/icp/script"
+	"sysafari.com/customs/tguard/utils"
+	"time"
+)
+
+// parseICPFileName Parse the duty party and month from the ICP file name, exp: BE0796544895_200601_02150405.xlsx
+func parseICPFileName(fileName string) (string, time.Time, error) {
+	fp := strings.Split(fileName, "_")
+	if len(fp) < 3 || fp[0] == "" {
+		return "", time.Time{}, fmt.Errorf("The ICP filename:%s invalid format(correct: BE0796544895_200601_02150405.xlsx)", fileName)
+	}
+	d, err := time.Parse(FileNameDateLayout, fp[1])
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("The ICP filename:%s invalid format(correct: BE0796544895_200601_02150405.xlsx)", fileName)
+	}
+	return fp[0], d, nil
+}
+
+// nextVersionFileName Make the file name of the next version of the ICP file.
+// The new version keeps the duty party and month of the previous one, only the generation time changes.
+func nextVersionFileName(fileName string) (string, error) {
+	dutyParty, d, err := parseICPFileName(fileName)
+	if err != nil {
+		return "", err
+	}
+	next := fmt.Sprintf("%s_%s_%s.xlsx", dutyParty, d.Format(FileNameDateLayout), time.Now().Format(FileNameTimeLayout))
+	if next == fileName {
+		return "", fmt.Errorf("The ICP:%s was generated just now, try again later", fileName)
+	}
+	return next, nil
+}
+
+// queryCustomsIDsOfICP Query the customs IDs which are contained in the ICP file
+func queryCustomsIDsOfICP(fileName string) ([]string, error) {
+	var customsIds []string
+	err := global.Db.Select(&customsIds, script.QueryCustomsIDsOfICPSql, fileName)
+	if err != nil {
+		return nil, err
+	}
+	return customsIds, nil
+}
+
+// ICPFilePath The full path of the ICP file, the file is saved in the directory of its month
+func ICPFilePath(fileName string) (string, error) {
+	_, d, err := parseICPFileName(fileName)
+	if err != nil {
+		return "", err
+	}
+	year, month := utils.GetCurrentYearMonth(d)
+	return filepath.Join(viper.GetString("icp.save-dir"), year, month, fileName), nil
+}
+
+// readCustomsIDsFromWorkbook Read the customs IDs(Invoice Number column) from the ICP sheet of the ICP file
+func readCustomsIDsFromWorkbook(fileName string) ([]string, error) {
+	fp, err := ICPFilePath(fileName)
+	if err != nil {
+		return nil, err
+	}
+	if !utils.IsExists(fp) {
+		return nil, nil
+	}
+
+	file, err := excelize.OpenFile(fp)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	for _, sheetName := range file.GetSheetList() {
+		if !strings.HasPrefix(sheetName, "ICP_") {
+			continue
+		}
+		// G: Invoice Number
+		cols, err := file.GetCols(sheetName)
+		if err != nil {
+			return nil, err
+		}
+		if len(cols) < 7 {
+			return nil, nil
+		}
+		var customsIds []string
+		for i, id := range cols[6] {
+			if i == 0 || id == "" || utils.In(id, customsIds) {
+				continue
+			}
+			customsIds = append(customsIds, id)
+		}
+		return customsIds, nil
+	}
+	return nil, fmt.Errorf("The ICP sheet not found in the ICP file %s", fileName)
+}
+
+// loadCustomsIDsOfICP Load the customs IDs contained in the ICP file.
+// The relations saved in service_icp_customs are merged with the customs in the ICP workbook,
+// the ICP files saved before every customs was recorded may miss some relations.
+func loadCustomsIDsOfICP(fileName string) ([]string, error) {
+	customsIds, err := queryCustomsIDsOfICP(fileName)
+	if err != nil {
+		fmt.Printf("Query customs of ICP %s failed, try to read the ICP file. error: %v \n", fileName, err)
+	}
+	workbookIds, wbErr := readCustomsIDsFromWorkbook(fileName)
+	if wbErr != nil {
+		if len(customsIds) > 0 {
+			fmt.Printf("Read customs of ICP %s from the ICP file failed, use the saved relations. error: %v \n", fileName, wbErr)
+			return customsIds, nil
+		}
+		return nil, wbErr
+	}
+	for _, id := range workbookIds {
+		if !utils.In(id, customsIds) {
+			customsIds = append(customsIds, id)
+		}
+	}
+	return customsIds, nil
+}
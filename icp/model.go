@@ -2,6 +2,7 @@ package icp
 
 import (
 	"database/sql"
+	"sysafari.com/customs/tguard/decimal"
 )
 
 // CustomsICPBase Base info of customs icp
@@ -17,22 +18,24 @@ type CustomsICPBase struct {
 
 // CustomsICPTax Tax info of customs
 type CustomsICPTax struct {
-	TaxType            string         `db:"tax_type"`
-	ItemNumber         string         `db:"itemnr"`
-	Destined           string         `db:"destined"`
-	LocalCurrencyValue float64        `db:"declared_amount"`
-	ImportDuty         float64        `db:"importDuty"`
-	DutchCost          string         `db:"dutchCost"`
-	DutchVat           string         `db:"dutchVat"`
-	CountryPreFix      string         `db:"countryPreFix"`
-	ProcessCode        string         `db:"process_code"`
-	InvoiceDate        string         `db:"invoiceDate"`
-	ProductNo          string         `db:"product_no"`
-	HsCode             sql.NullString `db:"hs_code"`
-	NetWeight          float64        `db:"net_weight"`
-	Quantity           int            `db:"quantity"`
-	Description        sql.NullString `db:"description"`
-	Currency           string         `db:"currency"`
+	TaxType            string          `db:"tax_type"`
+	ItemNumber         string          `db:"itemnr"`
+	Destined           string          `db:"destined"`
+	LocalCurrencyValue decimal.Decimal `db:"declared_amount"`
+	ImportDuty         decimal.Decimal `db:"importDuty"`
+	DutchCost          decimal.Text    `db:"dutchCost"`
+	DutchVat           decimal.Text    `db:"dutchVat"`
+	CountryPreFix      string          `db:"countryPreFix"`
+	ProcessCode        string          `db:"process_code"`
+	InvoiceDate        string          `db:"invoiceDate"`
+	ProductNo          string          `db:"product_no"`
+	HsCode             sql.NullString  `db:"hs_code"`
+	NetWeight          decimal.Decimal `db:"net_weight"`
+	Quantity           int             `db:"quantity"`
+	Description        sql.NullString  `db:"description"`
+	Currency           string          `db:"currency"`
+	OriginalCurrency   string          `db:"original_currency"`
+	OriginalAmount     decimal.Decimal `db:"original_amount"`
 }
 
 // CustomsICPImporter The importer address info for customs
@@ -62,6 +65,14 @@ type ServiceICP struct {
 	Status    bool   `db:"status"`
 	VatNote   string `db:"vat_note"`
 	IsNewest  bool   `db:"is_newest"`
+	// PreviousName The ICP file which this file is a new version of, empty for the first version
+	PreviousName string `db:"previous_name"`
+	// RateSource Where the exchange rates used by the ICP come from
+	RateSource string `db:"rate_source"`
+	// OssKey The object key of the uploaded ICP file, empty if not uploaded
+	OssKey string `db:"oss_key"`
+	// VatNoteOssKey The object key of the uploaded vat-note zip, empty if not uploaded
+	VatNoteOssKey string `db:"vat_note_oss_key"`
 }
 
 // ServiceICPCustoms sysafari.service_icp_customs
@@ -81,36 +92,41 @@ type TaxObject struct {
 	ItemNumber           string `db:"itemnr"`
 	Destined             string `db:"destinedNumber"`
 	ProcessCode          string
-	ProcessStatus        int            `db:"processingStatus"`
-	CustomsId            string         `db:"customs_id"`
-	InvoiceDate          string         `db:"invoiceDate"`
-	Currency             string         `db:"currency"`
-	LocalCurrencyValue   float64        `db:"localCurrencyValue"`
-	ImportDuty           float64        `db:"importDuty"`
-	DutchCost            string         `db:"dutchCost"`
-	DutchVat             string         `db:"dutchVat"`
-	HsCode               sql.NullString `db:"hsCode"`
-	NetWeight            float64        `db:"netWeight"`
-	Quantity             int            `db:"quantity"`
-	CountryPreFix        string         `db:"countryPreFix"`
-	DutyParty            sql.NullString `db:"dutyParty"`
-	PartnerName          string         `db:"partnerName"`
-	CountryOfDestination string         `db:"countryOfDestination"`
-	VatNo                string         `db:"vatNo"`
-	EoriNo               sql.NullString `db:"eoriNo"`
-	ImportAddressCode    string         `db:"importAddressCode"`
-	AddressCode          string         `db:"addressCode"`
-	AddressDetail        sql.NullString `db:"addressDetail"`
-	PostalCode           sql.NullString `db:"postalCode"`
-	City                 string         `db:"city"`
-	ProductNo            string         `db:"productNo"`
-	Description          sql.NullString `db:"description"`
-	Mrn                  string         `db:"mrn"`
-	Mode                 string         `db:"mode"`
-	CompanyName          string         `db:"companyName"`
+	ProcessStatus        int             `db:"processingStatus"`
+	CustomsId            string          `db:"customs_id"`
+	InvoiceDate          string          `db:"invoiceDate"`
+	Currency             string          `db:"currency"`
+	LocalCurrencyValue   decimal.Decimal `db:"localCurrencyValue"`
+	ImportDuty           decimal.Decimal `db:"importDuty"`
+	DutchCost            decimal.Text    `db:"dutchCost"`
+	DutchVat             decimal.Text    `db:"dutchVat"`
+	HsCode               sql.NullString  `db:"hsCode"`
+	NetWeight            decimal.Decimal `db:"netWeight"`
+	Quantity             int             `db:"quantity"`
+	CountryPreFix        string          `db:"countryPreFix"`
+	DutyParty            sql.NullString  `db:"dutyParty"`
+	PartnerName          string          `db:"partnerName"`
+	CountryOfDestination string          `db:"countryOfDestination"`
+	VatNo                string          `db:"vatNo"`
+	EoriNo               sql.NullString  `db:"eoriNo"`
+	ImportAddressCode    string          `db:"importAddressCode"`
+	AddressCode          string          `db:"addressCode"`
+	AddressDetail        sql.NullString  `db:"addressDetail"`
+	PostalCode           sql.NullString  `db:"postalCode"`
+	City                 string          `db:"city"`
+	ProductNo            string          `db:"productNo"`
+	Description          sql.NullString  `db:"description"`
+	Mrn                  string          `db:"mrn"`
+	Mode                 string          `db:"mode"`
+	CompanyName          string          `db:"companyName"`
 	// Yes
 	HasInspectionFine string `db:"hasInspectionFine"`
 	InICPFile         string `db:"hasInIcp"`
+	// OriginalCurrency The currency of the declared value before converting to EUR
+	OriginalCurrency string          `db:"originalCurrency"`
+	OriginalAmount   decimal.Decimal `db:"originalAmount"`
+	ExchangeRate     decimal.Decimal
+	EURValue         decimal.Decimal
 }
 
 // TaxFileObject The object of the tax file
@@ -132,6 +148,15 @@ type PodFileObject struct {
 	MinIndexNo  string         `db:"min_index_no"`
 	TrackingNo  string         `db:"tracking_no"`
 	PodFileLink sql.NullString `db:"uri"`
+	// LocalPath The relative path of the local copy of the POD file, empty if not fetched
+	LocalPath string
+	// FetchError The error of fetching the POD file
+	FetchError string
+}
+
+// Missing Whether the POD file is missing, no link or failed to fetch
+func (p PodFileObject) Missing() bool {
+	return p.PodFileLink.String == "" || p.FetchError != ""
 }
 
 type CustomsServiceKeyObject struct {
@@ -0,0 +1,422 @@
+package icp
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/xuri/excelize/v2"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"sysafari.com/customs/tguard/archive"
+	"sysafari.com/customs/tguard/utils"
+	"sysafari.com/customs/tguard/workbook"
+)
+
+const (
+	DocumentKindTaxReceipt  = "TAX_RECEIPT"
+	DocumentKindPod         = "POD"
+	DocumentKindVatNote     = "VAT_NOTE"
+	DocumentKindTransferDoc = "TRANSFER_DOC"
+
+	// IndexSheetName The sheet of the documents in the submission package
+	IndexSheetName = "INDEX"
+
+	// PackageFileSuffix The suffix of the submission package, exp: BE0796544895_202209_01154020-package.zip
+	PackageFileSuffix = "-package.zip"
+)
+
+// PackageDocument A supporting document in the submission package
+type PackageDocument struct {
+	Kind      string `json:"kind"`
+	CustomsId string `json:"customs_id"`
+	Mrn       string `json:"mrn"`
+	Source    string `json:"source"`
+	// Path The relative path in the package, empty if the document is missing
+	Path  string `json:"path"`
+	Error string `json:"error"`
+}
+
+// SubmissionPackage The ICP workbook with the tax receipts, POD files and vat notes, submitted to tax agency as one zip
+type SubmissionPackage struct {
+	// IcpFileName The ICP file to package
+	IcpFileName string `json:"icp_file_name"`
+	// FileName The package zip file name
+	FileName string `json:"file_name"`
+	// FilePath The full path of the package zip
+	FilePath  string            `json:"file_path"`
+	Documents []PackageDocument `json:"documents"`
+	Errors    []string          `json:"errors"`
+}
+
+// PackageFilePath The full path of the submission package, it is saved next to the ICP file
+func PackageFilePath(fileName string) (string, error) {
+	if !strings.HasSuffix(fileName, PackageFileSuffix) {
+		return "", fmt.Errorf("The package filename:%s invalid format(correct: BE0796544895_200601_02150405%s)", fileName, PackageFileSuffix)
+	}
+	icpPath, err := ICPFilePath(strings.TrimSuffix(fileName, PackageFileSuffix) + ".xlsx")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(filepath.Dir(icpPath), fileName), nil
+}
+
+// MakeSubmissionPackage Make the submission package of the ICP file
+func MakeSubmissionPackage(icpFileName string) *SubmissionPackage {
+	p := &SubmissionPackage{
+		IcpFileName: icpFileName,
+		FileName:    strings.TrimSuffix(icpFileName, ".xlsx") + PackageFileSuffix,
+	}
+	p.make()
+	return p
+}
+
+// make Copy the ICP workbook into a work directory with the documents fetched, then compress it into the package.
+// The workbook is the ICP file as it was generated, only the links of the documents are rewritten to the local copies.
+func (p *SubmissionPackage) make() {
+	log.Printf("Making submission package of ICP %s \n", p.IcpFileName)
+	dutyParty, monthDt, err := parseICPFileName(p.IcpFileName)
+	if err != nil {
+		p.Errors = append(p.Errors, err.Error())
+		return
+	}
+	icpPath, err := ICPFilePath(p.IcpFileName)
+	if err != nil {
+		p.Errors = append(p.Errors, err.Error())
+		return
+	}
+	if !utils.IsExists(icpPath) {
+		p.Errors = append(p.Errors, fmt.Sprintf("The ICP file %s not exists.", p.IcpFileName))
+		return
+	}
+	packagePath, err := PackageFilePath(p.FileName)
+	if err != nil {
+		p.Errors = append(p.Errors, err.Error())
+		return
+	}
+	p.FilePath = packagePath
+	saveDir := filepath.Dir(packagePath)
+
+	customsIds, err := loadCustomsIDsOfICP(p.IcpFileName)
+	if err != nil || len(customsIds) == 0 {
+		p.Errors = append(p.Errors, fmt.Sprintf("Can not load customs of the ICP %s, %v", p.IcpFileName, err))
+		return
+	}
+
+	file, err := excelize.OpenFile(icpPath)
+	if err != nil {
+		p.Errors = append(p.Errors, fmt.Sprintf("Open ICP file %s failed: %v", p.IcpFileName, err))
+		return
+	}
+	defer file.Close()
+
+	workDir, err := os.MkdirTemp(saveDir, ".package-"+strings.TrimSuffix(p.IcpFileName, ".xlsx")+"-")
+	if err != nil {
+		p.Errors = append(p.Errors, fmt.Sprintf("Create package work dir in %s failed: %v", saveDir, err))
+		return
+	}
+	defer func() {
+		if err := os.RemoveAll(workDir); err != nil {
+			fmt.Printf("Remove package work dir: %s failed: %v \n", workDir, err)
+		}
+	}()
+
+	icp := &FileOfICP{
+		FileName:   p.IcpFileName,
+		DutyParty:  dutyParty,
+		Month:      monthDt.Format("2006-01"),
+		CustomsIDs: customsIds,
+		FilePath:   filepath.Join(workDir, p.IcpFileName),
+	}
+	p.fetchSupportingDocuments(icp, file, filepath.Dir(icpPath), workDir)
+
+	// 重新打包时替换旧的索引表
+	if file.GetSheetIndex(IndexSheetName) != -1 {
+		file.DeleteSheet(IndexSheetName)
+	}
+	if err = FillIndexSheet(file, IndexSheetName, p.IcpFileName, p.Documents); err != nil {
+		icp.Errors = append(icp.Errors, fmt.Sprintf("Fill index sheet failed: %v", err))
+	}
+	if len(icp.Errors) > 0 {
+		p.Errors = append(p.Errors, icp.Errors...)
+		return
+	}
+	if err = file.SaveAs(icp.FilePath); err != nil {
+		p.Errors = append(p.Errors, fmt.Sprintf("Save ICP file into package failed: %v", err))
+		return
+	}
+
+	tmpZip, err := os.CreateTemp(saveDir, p.FileName+".*.tmp")
+	if err != nil {
+		p.Errors = append(p.Errors, err.Error())
+		return
+	}
+	_ = tmpZip.Close()
+	defer os.Remove(tmpZip.Name())
+
+	err = archive.ZipDir(workDir, tmpZip.Name(), archive.WithLevel(viper.GetInt("zip.deflate-level")))
+	if err == nil {
+		err = os.Rename(tmpZip.Name(), p.FilePath)
+	}
+	if err != nil {
+		p.Errors = append(p.Errors, fmt.Sprintf("Zip submission package %s failed: %v", p.FileName, err))
+	}
+}
+
+// documentLink A link cell of the ICP workbook and the document it points to
+type documentLink struct {
+	Sheet string
+	Axis  string
+	Doc   PackageDocument
+	job   *documentJob
+}
+
+// fetchSupportingDocuments Fetch the tax receipts, POD files and vat notes into the work directory.
+// The documents are the links in the ICP workbook, the link cells are rewritten to the relative paths inside the package,
+// the missing documents keep the original links.
+func (p *SubmissionPackage) fetchSupportingDocuments(icp *FileOfICP, file *excelize.File, icpDir string, workDir string) {
+	links, err := readDocumentLinks(file)
+	if err != nil {
+		icp.Errors = append(icp.Errors, fmt.Sprintf("Read document links of ICP %s failed: %v", icp.FileName, err))
+		return
+	}
+	utils.CreateDir(filepath.Join(workDir, "tax-receipt"))
+	utils.CreateDir(filepath.Join(workDir, PodDirName))
+
+	// 同一个文件只获取一次，ICP文件旁已有的本地副本直接复制
+	planned := map[string]*documentJob{}
+	var jobs []*documentJob
+	for _, l := range links {
+		switch {
+		case l.Doc.Source == "":
+			l.Doc.Error, l.Doc.Path = "no "+documentKindName(l.Doc.Kind)+" link", ""
+		case planned[l.Doc.Path] != nil:
+			l.job = planned[l.Doc.Path]
+		case isLocalDocument(icpDir, l.Doc.Source):
+			l.Doc.Path = l.Doc.Source
+			dst := filepath.Join(workDir, filepath.FromSlash(l.Doc.Path))
+			if !utils.IsDir(filepath.Dir(dst)) {
+				utils.CreateDir(filepath.Dir(dst))
+			}
+			if err := utils.Copy(filepath.Join(icpDir, filepath.FromSlash(l.Doc.Source)), dst); err != nil {
+				l.Doc.Error, l.Doc.Path = err.Error(), ""
+			}
+		default:
+			l.job = &documentJob{
+				Key:       l.Doc.CustomsId,
+				Kind:      l.Doc.Kind,
+				Uri:       l.Doc.Source,
+				SavePath:  filepath.Join(workDir, filepath.FromSlash(l.Doc.Path)),
+				VerifyPDF: l.Doc.Kind == DocumentKindTaxReceipt,
+			}
+			planned[l.Doc.Path] = l.job
+			jobs = append(jobs, l.job)
+		}
+	}
+
+	log.Printf("Fetching %d documents of ICP %s \n", len(jobs), icp.FileName)
+	fetchDocuments(jobs)
+
+	profile := workbook.LoadProfile()
+	for _, l := range links {
+		if l.job != nil && l.job.Error != "" {
+			l.Doc.Error, l.Doc.Path = l.job.Error, ""
+		}
+		if l.Doc.Error == "" {
+			if err := profile.SetLink(file, l.Sheet, l.Axis, l.Doc.Path); err != nil {
+				icp.Errors = append(icp.Errors, fmt.Sprintf("Rewrite link %s!%s failed: %v", l.Sheet, l.Axis, err))
+			}
+		}
+		p.Documents = append(p.Documents, l.Doc)
+	}
+
+	// vat note
+	if viper.GetBool("zip.vat-note-open") && icp.DutyNeedVatNote() {
+		results := newVatNoteDownloader().Download(vatNoteTasks(icp.CustomsIDs, workDir))
+		for _, r := range results {
+			kind := DocumentKindVatNote
+			if r.Kind == "transferDoc" {
+				kind = DocumentKindTransferDoc
+			}
+			doc := PackageDocument{Kind: kind, CustomsId: r.Key, Source: r.Uri, Error: r.Error}
+			if !r.Failed() {
+				rel, _ := filepath.Rel(workDir, r.SavePath)
+				doc.Path = filepath.ToSlash(rel)
+			}
+			p.Documents = append(p.Documents, doc)
+		}
+	}
+}
+
+// readDocumentLinks Read the links of the tax receipts(TAX sheet) and POD files(POD sheet) in the ICP workbook,
+// the path of every document inside the package is planned by the customs ID or the file name of the link
+func readDocumentLinks(file *excelize.File) ([]*documentLink, error) {
+	var links []*documentLink
+
+	// ICP表 G: Invoice Number, AE: MRN, 税单表只有MRN
+	mrnCustoms := map[string]string{}
+	if sheet := findSheet(file, "ICP_"); sheet != "" {
+		rows, err := file.GetRows(sheet)
+		if err != nil {
+			return nil, err
+		}
+		for i, row := range rows {
+			if i == 0 || len(row) <= 30 {
+				continue
+			}
+			mrnCustoms[row[30]] = row[6]
+		}
+	}
+
+	// 税单表 A: SN, B: MRN, C: Tax receipt Link
+	if sheet := findSheet(file, "TAX_"); sheet != "" {
+		rows, err := file.GetRows(sheet)
+		if err != nil {
+			return nil, err
+		}
+		for i := 1; i < len(rows); i++ {
+			axis := fmt.Sprintf("C%d", i+1)
+			link, err := cellLink(file, sheet, axis)
+			if err != nil {
+				return nil, err
+			}
+			mrn := cellValue(rows[i], 1)
+			name := mrnCustoms[mrn]
+			if name == "" {
+				name = mrn
+			}
+			links = append(links, &documentLink{Sheet: sheet, Axis: axis, Doc: PackageDocument{
+				Kind:      DocumentKindTaxReceipt,
+				CustomsId: mrnCustoms[mrn],
+				Mrn:       mrn,
+				Source:    link,
+				Path:      filepath.ToSlash(filepath.Join("tax-receipt", name+"_tax_receipt.pdf")),
+			}})
+		}
+	}
+
+	// POD表 C: Invoice Number, D: MRN, E: Tracing No., G: POD Link
+	if sheet := findSheet(file, "POD_"); sheet != "" {
+		rows, err := file.GetRows(sheet)
+		if err != nil {
+			return nil, err
+		}
+		podPaths := map[string]string{}
+		usedNames := map[string]string{}
+		for i := 1; i < len(rows); i++ {
+			axis := fmt.Sprintf("G%d", i+1)
+			link, err := cellLink(file, sheet, axis)
+			if err != nil {
+				return nil, err
+			}
+			// 同名不同链接的POD文件加上运单号区分
+			rel := podPaths[link]
+			if link != "" && rel == "" {
+				name := documentName(link)
+				if other, ok := usedNames[name]; name == "" || (ok && other != link) {
+					name = cellValue(rows[i], 4) + "_" + name
+				}
+				usedNames[name] = link
+				rel = filepath.ToSlash(filepath.Join(PodDirName, name))
+				podPaths[link] = rel
+			}
+			links = append(links, &documentLink{Sheet: sheet, Axis: axis, Doc: PackageDocument{
+				Kind:      DocumentKindPod,
+				CustomsId: cellValue(rows[i], 2),
+				Mrn:       cellValue(rows[i], 3),
+				Source:    link,
+				Path:      rel,
+			}})
+		}
+	}
+	return links, nil
+}
+
+// findSheet The first sheet whose name starts with the prefix, exp: POD_, empty if not found
+func findSheet(file *excelize.File, prefix string) string {
+	for _, sheetName := range file.GetSheetList() {
+		if strings.HasPrefix(sheetName, prefix) {
+			return sheetName
+		}
+	}
+	return ""
+}
+
+// cellLink The link of the cell, the target of the hyperlink if it is set, otherwise the value of the cell
+func cellLink(file *excelize.File, sheetName, axis string) (string, error) {
+	ok, target, err := file.GetCellHyperLink(sheetName, axis)
+	if err != nil {
+		return "", err
+	}
+	if ok && target != "" {
+		return target, nil
+	}
+	return file.GetCellValue(sheetName, axis)
+}
+
+// cellValue The value of the column in the row, empty if the row is shorter
+func cellValue(row []string, col int) string {
+	if col < len(row) {
+		return strings.TrimSpace(row[col])
+	}
+	return ""
+}
+
+// isLocalDocument Whether the link is a path relative to the ICP file and the file exists, exp: the embedded POD files
+func isLocalDocument(icpDir string, link string) bool {
+	if isHttpUri(link) || filepath.IsAbs(link) || strings.HasPrefix(filepath.Clean(filepath.FromSlash(link)), "..") {
+		return false
+	}
+	p := filepath.Join(icpDir, filepath.FromSlash(link))
+	return utils.IsExists(p) && !utils.IsDir(p)
+}
+
+// documentKindName The readable name of the document kind, used in the messages
+func documentKindName(kind string) string {
+	switch kind {
+	case DocumentKindTaxReceipt:
+		return "tax receipt"
+	case DocumentKindPod:
+		return "POD"
+	}
+	return strings.ToLower(kind)
+}
+
+// FillIndexSheet fill the index sheet of the documents in the submission package
+func FillIndexSheet(file *excelize.File, sheetName string, icpFileName string, documents []PackageDocument) error {
+	log.Println("Index sheet name: ", sheetName)
+	profile := workbook.LoadProfile()
+	file.NewSheet(sheetName)
+
+	IndexSheetHeaders := &[]interface{}{"SN", "Type", "Invoice Number", "MRN", "File", "Source", "Status"}
+
+	err := file.SetSheetRow(sheetName, "A1", IndexSheetHeaders)
+	if err != nil {
+		fmt.Println(err)
+		return err
+	}
+
+	rows := append([]PackageDocument{{Kind: "ICP", Path: icpFileName}}, documents...)
+	for i, datum := range rows {
+		sn := i + 1
+		idx := sn + 1
+		status := "OK"
+		if datum.Error != "" {
+			status = "MISSING: " + datum.Error
+		}
+
+		err = file.SetCellInt(sheetName, fmt.Sprintf("A%d", idx), sn)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("B%d", idx), datum.Kind)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("C%d", idx), datum.CustomsId)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("D%d", idx), datum.Mrn)
+		err = profile.SetLink(file, sheetName, fmt.Sprintf("E%d", idx), datum.Path)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("F%d", idx), datum.Source)
+		err = file.SetCellStr(sheetName, fmt.Sprintf("G%d", idx), status)
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return profile.ApplySheet(file, sheetName)
+}
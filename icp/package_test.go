@@ -0,0 +1,84 @@
+package icp
+
+import (
+	"database/sql"
+	"github.com/xuri/excelize/v2"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestReadDocumentLinks(t *testing.T) {
+	file := excelize.NewFile()
+	taxData := []TaxObject{
+		{CustomsId: "C1", Mrn: "M1"},
+		{CustomsId: "C2", Mrn: "M2"},
+	}
+	if err := FillTaxSheet(file, "ICP_BE01_202209", taxData); err != nil {
+		t.Fatal(err)
+	}
+	taxFiles := []TaxFileObject{
+		{Mrn: "M1", TaxFileLink: "https://example.com/tax/M1.pdf"},
+		{Mrn: "M2", TaxFileLink: "tax/M2.pdf"},
+	}
+	if err := FillTaxFileSheet(file, "TAX_BE01_202209", taxFiles); err != nil {
+		t.Fatal(err)
+	}
+	pods := []PodFileObject{
+		{CustomsId: "C1", Mrn: "M1", TrackingNo: "T1", PodFileLink: sql.NullString{String: "https://a.example.com/pod.pdf", Valid: true}},
+		{CustomsId: "C1", Mrn: "M1", TrackingNo: "T2", PodFileLink: sql.NullString{String: "https://b.example.com/pod.pdf", Valid: true}},
+		{CustomsId: "C2", Mrn: "M2", TrackingNo: "T3"},
+	}
+	if err := FillPodSheet(file, "POD_BE01_202209", pods); err != nil {
+		t.Fatal(err)
+	}
+
+	links, err := readDocumentLinks(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []struct {
+		axis, kind, customsId, source, path string
+	}{
+		{"C2", DocumentKindTaxReceipt, "C1", "https://example.com/tax/M1.pdf", "tax-receipt/C1_tax_receipt.pdf"},
+		{"C3", DocumentKindTaxReceipt, "C2", "tax/M2.pdf", "tax-receipt/C2_tax_receipt.pdf"},
+		{"G2", DocumentKindPod, "C1", "https://a.example.com/pod.pdf", "pod/pod.pdf"},
+		// 同名不同链接加上运单号
+		{"G3", DocumentKindPod, "C1", "https://b.example.com/pod.pdf", "pod/T2_pod.pdf"},
+		{"G4", DocumentKindPod, "C2", "", ""},
+	}
+	if len(links) != len(want) {
+		t.Fatalf("got %d links, want %d", len(links), len(want))
+	}
+	for i, w := range want {
+		l := links[i]
+		if l.Axis != w.axis || l.Doc.Kind != w.kind || l.Doc.CustomsId != w.customsId || l.Doc.Source != w.source || l.Doc.Path != w.path {
+			t.Errorf("link %d = %s %s %s %s %s, want %s %s %s %s %s", i,
+				l.Axis, l.Doc.Kind, l.Doc.CustomsId, l.Doc.Source, l.Doc.Path,
+				w.axis, w.kind, w.customsId, w.source, w.path)
+		}
+	}
+}
+
+func TestIsLocalDocument(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "pod"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "pod", "a.pdf"), []byte("%PDF"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := map[string]bool{
+		"pod/a.pdf":                 true,
+		"pod/b.pdf":                 false,
+		"pod":                       false,
+		"../pod/a.pdf":              false,
+		"https://example.com/a.pdf": false,
+	}
+	for link, want := range tests {
+		if got := isLocalDocument(dir, link); got != want {
+			t.Errorf("isLocalDocument(%q) = %v, want %v", link, got, want)
+		}
+	}
+}
@@ -44,7 +44,9 @@ SELECT bct.tax_type,
        sca.net_weight,
        sca.quantity,
        bd.description,
-       'EUR'                                      AS currency
+       'EUR'                                      AS currency,
+       IFNULL(sca.currency, 'EUR')                AS original_currency,
+       IFNULL(sca.final_declared_value, bct.declared_amount) AS original_amount
 FROM log_clearance_process lcp
          INNER JOIN base_customs_tax bct ON bct.customs_id = lcp.customs_id AND
                                             IF(lcp.process_code = 'TAX', bct.processing_status = 4,
@@ -53,7 +53,9 @@ WHERE bc.customs_id = ?`
        sca.net_weight,
        sca.quantity,
        bd.description,
-       'EUR'                                      AS currency
+       'EUR'                                      AS currency,
+       IFNULL(sca.currency, 'EUR')                AS original_currency,
+       IFNULL(sca.final_declared_value, bct.declared_amount) AS original_amount
 FROM log_clearance_process lcp
          INNER JOIN base_customs_tax bct ON bct.customs_id = lcp.customs_id AND
                                             IF(lcp.process_code = 'TAX', bct.processing_status = 4,
@@ -80,7 +82,9 @@ ORDER BY bct.itemnr, bct.tax_type;`
        sca.net_weight,
        sca.quantity,
        bd.description,
-       'EUR'                                      AS currency
+       'EUR'                                      AS currency,
+       IFNULL(sca.currency, 'EUR')                AS original_currency,
+       IFNULL(sca.final_declared_value, bct.declared_amount) AS original_amount
 FROM log_clearance_process lcp
          INNER JOIN base_customs_tax bct ON bct.customs_id = lcp.customs_id AND
                                             IF(lcp.process_code = 'TAX', bct.processing_status = 4,
@@ -121,6 +125,9 @@ WHERE c.customs_id = ? ;`
 	// QueryCustomsHasInICPNameSql Query the ICP file name that already contains the Customs
 	QueryCustomsHasInICPNameSql = `	SELECT GROUP_CONCAT(distinct sic.icp_name) FROM service_icp_customs sic WHERE sic.customs_id = ? GROUP BY customs_id;`
 
+	// QueryCustomsIDsOfICPSql Query the customs IDs contained in the ICP file
+	QueryCustomsIDsOfICPSql = `SELECT DISTINCT customs_id FROM service_icp_customs WHERE icp_name = ? AND in_excel = 1;`
+
 	// QueryCustomsTrackingPodSql Query the customs' tracking pod
 	QueryCustomsTrackingPodSql = `SELECT b.bill_no,c.customs_id,
        c.mrn AS mrn, 
@@ -153,6 +160,20 @@ FROM base_reference_tracking t
          LEFT JOIN base_file bf ON bf.id = btli.file_id
 WHERE t.customs_id = ? ;`
 
+	// QueryCustomsTrackingLogisticsSql Query whether the tracking numbers of the customs have logistics info
+	QueryCustomsTrackingLogisticsSql = `SELECT t.tracking_no,
+       COUNT(btli.tracking_no) > 0 AS has_logistics
+FROM base_reference_tracking t
+         LEFT JOIN base_track_logistics_info btli ON t.tracking_no = btli.tracking_no
+WHERE t.customs_id = ?
+GROUP BY t.tracking_no;`
+
+	// QueryCustomsTaxProcessCodesSql Query the tax process codes (TAX, TMP_TAX) of the customs
+	QueryCustomsTaxProcessCodesSql = `SELECT DISTINCT process_code
+FROM log_clearance_process
+WHERE customs_id = ?
+  AND (process_code = 'TAX' OR process_code = 'TMP_TAX');`
+
 	// QueryCustomsHasInspectionFineSql 查询报关单是否有过查验罚款
 	QueryCustomsHasInspectionFineSql = `SELECT COUNT(1) FROM log_clearance_process WHERE customs_id = ? and process_code='INSPECTION_FINE';`
 
@@ -162,9 +183,16 @@ WHERE t.customs_id = ? ;`
 	// UpdateIcpIsNewestSql 更新ICP为非最新。便于新生成的ICP文件成为最新
 	UpdateIcpIsNewestSql = `UPDATE service_icp SET is_newest = 0 WHERE duty_part = ? AND year = ? AND month = ?;`
 
+	// UpdateIcpVatNoteSql 更新最新ICP的vat-note压缩包
+	UpdateIcpVatNoteSql = `UPDATE service_icp SET vat_note = ?, vat_note_oss_key = ? WHERE duty_part = ? AND year = ? AND month = ? AND is_newest = 1;`
+
 	// InsertServiceICP Insert row into service_icp
-	InsertServiceICP = `INSERT INTO service_icp (duty_part, name, year, month, icp_date,total, status, vat_note, is_newest) 
-values (:duty_part, :name, :year, :month, :icp_date,:total,:status,:vat_note,:is_newest);`
+	InsertServiceICP = `INSERT INTO service_icp (duty_part, name, previous_name, year, month, icp_date,total, status, vat_note, is_newest, rate_source, oss_key, vat_note_oss_key) 
+values (:duty_part, :name, :previous_name, :year, :month, :icp_date,:total,:status,:vat_note,:is_newest,:rate_source,:oss_key,:vat_note_oss_key);`
+
+	// InsertServiceICPSummary Insert rows into service_icp_summary
+	InsertServiceICPSummary = `INSERT INTO service_icp_summary (icp_name, dimension, dimension_key, customs_total, mrn_total, item_total, local_currency_value, import_duty) 
+values (:icp_name, :dimension, :dimension_key, :customs_total, :mrn_total, :item_total, :local_currency_value, :import_duty);`
 
 	// InsertServiceICPCustoms Insert row into service_icp_customs
 	InsertServiceICPCustoms = `INSERT INTO service_icp_customs (icp_name, xml_id, customs_id, tax_type,  in_excel) 
@@ -4,8 +4,12 @@ import (
 	"fmt"
 	"github.com/spf13/viper"
 	"log"
+	"path/filepath"
+	"strings"
 	"sysafari.com/customs/tguard/global"
 	"sysafari.com/customs/tguard/icp/script"
+	"sysafari.com/customs/tguard/utils"
+	"time"
 )
 
 // MakeICPForOneMonth Make ICP for one month
@@ -22,17 +26,17 @@ func MakeICPForOneMonth(month string) {
 	log.Printf("There are %d duty party in this month %s \n", len(dutyParties), month)
 
 	for _, dutyParty := range dutyParties {
-		filename, errs := MakeICPForDutyPart(dutyParty, month)
-		if len(errs) > 0 {
-			log.Printf("Error creating ICP for duty party %s in the month %s, erros: %v\n", dutyParty, month, errs)
+		icp := MakeICPForDutyPart(dutyParty, month)
+		if len(icp.Errors) > 0 {
+			log.Printf("Error creating ICP for duty party %s in the month %s, erros: %v\n", dutyParty, month, icp.Errors)
 		} else {
-			log.Printf("Generat ICP for duty party %s in the month %s success ,the filename: %s\n", dutyParty, month, filename)
+			log.Printf("Generat ICP for duty party %s in the month %s success ,the filename: %s\n", dutyParty, month, icp.FileName)
 		}
 	}
 }
 
-// MakeICPForDutyPart Make ICP file for the duty party
-func MakeICPForDutyPart(dutyParty string, month string) (string, []string) {
+// MakeICPForDutyPart Make ICP file for the duty party, the file name and errors are in the returned ICP
+func MakeICPForDutyPart(dutyParty string, month string) *FileOfICP {
 	log.Printf("Making ICP for duty party %s in the month %s \n", dutyParty, month)
 	icp := &FileOfICP{
 		DutyParty: dutyParty,
@@ -47,13 +51,50 @@ func MakeICPForDutyPart(dutyParty string, month string) (string, []string) {
 	if openVatNote {
 		fmt.Println("Need check whether duty need vat-note..")
 		if icp.DutyNeedVatNote() {
-			icp.GenerateVatNotesZip()
+			_ = icp.GenerateVatNotesZip()
 		}
 	}
 
-	filename := icp.GenerateICP()
-	errs := icp.Errors
-	return filename, errs
+	icp.GenerateICP()
+	return icp
+}
+
+// MakeVatNoteForDutyParty Make the vat-note zip of the duty party in the month on demand.
+// If no customs IDs are specified, all customs of the duty party in the month are used.
+// The zip file name and errors are in the returned ICP.
+func MakeVatNoteForDutyParty(dutyParty string, month string, customsIds []string) *FileOfICP {
+	log.Printf("Making vat-note zip for duty party %s in the month %s \n", dutyParty, month)
+	icp := &FileOfICP{
+		DutyParty:  dutyParty,
+		Month:      month,
+		CustomsIDs: customsIds,
+	}
+	if len(icp.CustomsIDs) == 0 {
+		icp.QueryCustomsIDs()
+	}
+	if len(icp.Errors) > 0 {
+		return icp
+	}
+
+	if err := icp.GenerateVatNotesZip(); err != nil {
+		icp.Errors = append(icp.Errors, fmt.Sprintf("Make vat-note zip failed: %v", err))
+		return icp
+	}
+
+	icp.saveVatNoteIntoDB()
+	return icp
+}
+
+// VatNoteZipFilePath The full path of the vat-note zip, exp: 2022-09-BE0796544895-vatnote.zip
+func VatNoteZipFilePath(fileName string) (string, error) {
+	if len(fileName) < 7 {
+		return "", fmt.Errorf("The vat-note zip filename:%s invalid format(correct: 2006-01-BE0796544895-vatnote.zip)", fileName)
+	}
+	d, err := time.Parse("2006-01", fileName[:7])
+	if err != nil || !strings.HasSuffix(fileName, "-vatnote.zip") {
+		return "", fmt.Errorf("The vat-note zip filename:%s invalid format(correct: 2006-01-BE0796544895-vatnote.zip)", fileName)
+	}
+	return filepath.Join(viper.GetString("zip.vat-note-dir"), d.Format("2006"), fileName), nil
 }
 
 // MakeICPByVatNo Make ICP file by VAt No.
@@ -69,3 +110,96 @@ func MakeICPByVatNo(vatNo string) (string, []string) {
 	fmt.Println("errors: ", errs)
 	return filename, errs
 }
+
+// RemoveFromICP Regenerate the ICP file without the specified customs IDs.
+// The ICP file is not overwritten, a new version of the file will be generated.
+func RemoveFromICP(fileName string, customsIds []string) *FileOfICP {
+	log.Printf("Removing customs %v from ICP %s \n", customsIds, fileName)
+	icp := &FileOfICP{
+		PreviousFileName: fileName,
+	}
+
+	existIds, err := loadCustomsIDsOfICP(fileName)
+	if err != nil || len(existIds) == 0 {
+		icp.Errors = append(icp.Errors, fmt.Sprintf("Can not query customs of the ICP %s, %v", fileName, err))
+		return icp
+	}
+
+	for _, id := range existIds {
+		if utils.In(id, customsIds) {
+			icp.RemovedCustomsIDs = append(icp.RemovedCustomsIDs, id)
+		} else {
+			icp.CustomsIDs = append(icp.CustomsIDs, id)
+		}
+	}
+	if len(icp.RemovedCustomsIDs) == 0 {
+		icp.Errors = append(icp.Errors, fmt.Sprintf("None of the customs %v is in the ICP %s", customsIds, fileName))
+		return icp
+	}
+	if len(icp.CustomsIDs) == 0 {
+		icp.Errors = append(icp.Errors, fmt.Sprintf("Can not remove all customs from the ICP %s", fileName))
+		return icp
+	}
+
+	newFileName, err := nextVersionFileName(fileName)
+	if err != nil {
+		icp.Errors = append(icp.Errors, err.Error())
+		return icp
+	}
+	icp.FileName = newFileName
+
+	icp.GenerateICP()
+	return icp
+}
+
+// AppendToICP Append the specified customs IDs to the ICP file.
+// The customs already in the ICP file are kept, a new version of the ICP file will be generated.
+// If the ICP file does not exist, a new ICP file with the specified file name will be created.
+func AppendToICP(fileName string, customsIds []string) *FileOfICP {
+	log.Printf("Appending customs %v to ICP %s \n", customsIds, fileName)
+	icp := &FileOfICP{}
+
+	existIds, err := loadCustomsIDsOfICP(fileName)
+	if err != nil {
+		icp.Errors = append(icp.Errors, fmt.Sprintf("Can not load customs of the ICP %s, %v", fileName, err))
+		return icp
+	}
+
+	icp.CustomsIDs = append(icp.CustomsIDs, existIds...)
+	for _, id := range customsIds {
+		if id == "" {
+			continue
+		}
+		if utils.In(id, existIds) {
+			if !utils.In(id, icp.ExistingCustomsIDs) {
+				icp.ExistingCustomsIDs = append(icp.ExistingCustomsIDs, id)
+			}
+			continue
+		}
+		if !utils.In(id, icp.AddedCustomsIDs) {
+			icp.AddedCustomsIDs = append(icp.AddedCustomsIDs, id)
+		}
+	}
+	icp.CustomsIDs = append(icp.CustomsIDs, icp.AddedCustomsIDs...)
+
+	if len(existIds) == 0 {
+		// ICP 文件不存在，使用指定的文件名创建
+		icp.FileName = fileName
+	} else {
+		if len(icp.AddedCustomsIDs) == 0 {
+			icp.FileName = fileName
+			log.Printf("All customs already in the ICP %s, nothing to append.\n", fileName)
+			return icp
+		}
+		newFileName, err := nextVersionFileName(fileName)
+		if err != nil {
+			icp.Errors = append(icp.Errors, err.Error())
+			return icp
+		}
+		icp.PreviousFileName = fileName
+		icp.FileName = newFileName
+	}
+
+	icp.GenerateICP()
+	return icp
+}
@@ -0,0 +1,103 @@
+package icp
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"log"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	// DefaultTaxReceiptLinkForNL The default tax receipt link template of the NL declarations
+	DefaultTaxReceiptLinkForNL = "https://board.sysafari.com/declarefile/-1/18-{customs_id}"
+	// DefaultTaxReceiptLinkForBE The default tax receipt link template of the BE declarations
+	DefaultTaxReceiptLinkForBE = "https://board.sysafari.com/declarefile-be?customsId={customs_id}&statusCode={status_code}"
+)
+
+// TaxReceiptLinkParams The values of the placeholders in the tax receipt link template
+type TaxReceiptLinkParams struct {
+	CustomsId      string
+	Mrn            string
+	DeclareCountry string
+	// TaxType 4: TAX, 114: TMP_TAX
+	TaxType int
+}
+
+// TaxReceiptLinkProvider Build the tax receipt link of the customs declared in a country
+type TaxReceiptLinkProvider interface {
+	Link(params TaxReceiptLinkParams) string
+}
+
+// TemplateLinkProvider Build the link by replacing the placeholders in the template:
+// {customs_id}, {mrn}, {declare_country}, {tax_type}, {status_code}
+type TemplateLinkProvider struct {
+	Template   string
+	StatusCode string
+}
+
+// Link Build the tax receipt link from the template
+func (p *TemplateLinkProvider) Link(params TaxReceiptLinkParams) string {
+	return strings.NewReplacer(
+		"{customs_id}", params.CustomsId,
+		"{mrn}", params.Mrn,
+		"{declare_country}", params.DeclareCountry,
+		"{tax_type}", strconv.Itoa(params.TaxType),
+		"{status_code}", p.StatusCode,
+	).Replace(p.Template)
+}
+
+var (
+	taxReceiptLinkProviders = map[string]TaxReceiptLinkProvider{}
+	taxReceiptLinkMu        sync.RWMutex
+	taxReceiptLinkOnce      sync.Once
+)
+
+// RegisterTaxReceiptLinkProvider Register the tax receipt link provider of the declare country, replace the existing one
+func RegisterTaxReceiptLinkProvider(country string, provider TaxReceiptLinkProvider) {
+	taxReceiptLinkMu.Lock()
+	defer taxReceiptLinkMu.Unlock()
+	taxReceiptLinkProviders[strings.ToUpper(country)] = provider
+}
+
+// loadTaxReceiptLinkProviders Register the providers of the icp.tax-receipt-links settings, exp:
+//
+//	icp:
+//	  tax-receipt-links:
+//	    BE:
+//	      template: https://board.sysafari.com/declarefile-be?customsId={customs_id}&statusCode={status_code}
+//	      status-code: "09"
+//
+// NL and BE use the default templates if they are not configured.
+func loadTaxReceiptLinkProviders() {
+	RegisterTaxReceiptLinkProvider("NL", &TemplateLinkProvider{Template: DefaultTaxReceiptLinkForNL})
+	RegisterTaxReceiptLinkProvider("BE", &TemplateLinkProvider{Template: DefaultTaxReceiptLinkForBE, StatusCode: "09"})
+
+	for country := range viper.GetStringMap("icp.tax-receipt-links") {
+		key := "icp.tax-receipt-links." + country
+		template := viper.GetString(key + ".template")
+		if template == "" {
+			log.Printf("The tax receipt link template of declare country %s is empty, ignored.\n", country)
+			continue
+		}
+		RegisterTaxReceiptLinkProvider(country, &TemplateLinkProvider{
+			Template:   template,
+			StatusCode: viper.GetString(key + ".status-code"),
+		})
+	}
+}
+
+// TaxReceiptLink Build the tax receipt link with the provider of the declare country.
+// Returns an error if no provider registered for the country.
+func TaxReceiptLink(params TaxReceiptLinkParams) (string, error) {
+	taxReceiptLinkOnce.Do(loadTaxReceiptLinkProviders)
+
+	taxReceiptLinkMu.RLock()
+	provider, ok := taxReceiptLinkProviders[strings.ToUpper(params.DeclareCountry)]
+	taxReceiptLinkMu.RUnlock()
+	if !ok {
+		return "", fmt.Errorf("no tax receipt link provider for declare country %q", params.DeclareCountry)
+	}
+	return provider.Link(params), nil
+}
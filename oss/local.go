@@ -0,0 +1,175 @@
+package oss
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+)
+
+// tmpFilePattern The temporary files of writeFile in progress, exp: a.pdf.123456.tmp
+var tmpFilePattern = regexp.MustCompile(`\.\d+\.tmp$`)
+
+// LocalStore The ObjectStore in a local directory, the object key is the relative path in the directory
+type LocalStore struct {
+	Root string
+	// BaseURL The URL the directory is served at, the signed URL is the file URL if it is empty
+	BaseURL string
+}
+
+// NewLocalStore Create the local store in the root directory
+func NewLocalStore(root string, baseURL string) (*LocalStore, error) {
+	if root == "" {
+		return nil, fmt.Errorf("the oss local dir is required by the local driver")
+	}
+	abs, err := filepath.Abs(root)
+	if err != nil {
+		return nil, err
+	}
+	return &LocalStore{Root: abs, BaseURL: baseURL}, nil
+}
+
+// objectPath The local path of the object, the key can not escape the root directory
+func (s *LocalStore) objectPath(key string) (string, error) {
+	clean := path.Clean("/" + strings.TrimPrefix(key, "/"))
+	if clean == "/" {
+		return "", fmt.Errorf("invalid object key %q", key)
+	}
+	return filepath.Join(s.Root, filepath.FromSlash(clean)), nil
+}
+
+// Get Open the local object
+func (s *LocalStore) Get(key string) (io.ReadCloser, error) {
+	p, err := s.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(p)
+}
+
+// GetToFile Copy the local object to the file
+func (s *LocalStore) GetToFile(key string, savePath string) error {
+	r, err := s.Get(key)
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	return writeFile(savePath, r)
+}
+
+// Put Save the content as the local object
+func (s *LocalStore) Put(key string, r io.Reader) error {
+	p, err := s.objectPath(key)
+	if err != nil {
+		return err
+	}
+	if err = os.MkdirAll(filepath.Dir(p), os.ModePerm); err != nil {
+		return err
+	}
+	return writeFile(p, r)
+}
+
+// Stat The metadata of the local object, the ETag is the MD5 of the content as OSS does
+func (s *LocalStore) Stat(key string) (*ObjectInfo, error) {
+	p, err := s.objectPath(key)
+	if err != nil {
+		return nil, err
+	}
+	return statFile(p, strings.TrimPrefix(key, "/"))
+}
+
+// List The local objects with the key prefix, sorted by key. The files being written are not listed.
+func (s *LocalStore) List(prefix string) ([]ObjectInfo, error) {
+	var objects []ObjectInfo
+	err := filepath.Walk(s.Root, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if !info.Mode().IsRegular() || tmpFilePattern.MatchString(info.Name()) {
+			return nil
+		}
+		rel, err := filepath.Rel(s.Root, p)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		o, err := statFile(p, key)
+		if os.IsNotExist(err) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		objects = append(objects, *o)
+		return nil
+	})
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, err
+}
+
+// SignURL The URL of the local object, the local store does not expire the URL
+func (s *LocalStore) SignURL(key string, _ time.Duration) (string, error) {
+	p, err := s.objectPath(key)
+	if err != nil {
+		return "", err
+	}
+	if s.BaseURL != "" {
+		rel, err := filepath.Rel(s.Root, p)
+		if err != nil {
+			return "", err
+		}
+		return strings.TrimRight(s.BaseURL, "/") + "/" + filepath.ToSlash(rel), nil
+	}
+	return (&url.URL{Scheme: "file", Path: filepath.ToSlash(p)}).String(), nil
+}
+
+// statFile The metadata of the file
+func statFile(p string, key string) (*ObjectInfo, error) {
+	info, err := os.Stat(p)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(p)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	h := md5.New()
+	if _, err = io.Copy(h, f); err != nil {
+		return nil, err
+	}
+	return &ObjectInfo{
+		Key:          key,
+		Size:         info.Size(),
+		ETag:         strings.ToUpper(hex.EncodeToString(h.Sum(nil))),
+		LastModified: info.ModTime(),
+	}, nil
+}
+
+// writeFile Write the content into a temporary file first, then rename it to the path
+func writeFile(p string, r io.Reader) error {
+	tmp, err := os.CreateTemp(filepath.Dir(p), filepath.Base(p)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	_, err = io.Copy(tmp, r)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), p)
+}
@@ -0,0 +1,123 @@
+package oss
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func newTestStore(t *testing.T, baseURL string) *LocalStore {
+	s, err := NewLocalStore(t.TempDir(), baseURL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return s
+}
+
+func TestLocalStoreKeyStaysUnderRoot(t *testing.T) {
+	s := newTestStore(t, "")
+	for _, key := range []string{"../x", "a/../../x", "/../../x", "./x"} {
+		p, err := s.objectPath(key)
+		if err != nil {
+			t.Fatalf("objectPath(%q) failed: %v", key, err)
+		}
+		if p != filepath.Join(s.Root, "x") {
+			t.Errorf("objectPath(%q) = %s, want %s", key, p, filepath.Join(s.Root, "x"))
+		}
+	}
+	for _, key := range []string{"", "/", ".."} {
+		if _, err := s.objectPath(key); err == nil {
+			t.Errorf("objectPath(%q) want an error", key)
+		}
+	}
+
+	if err := s.Put("../escape.txt", strings.NewReader("x")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(s.Root), "escape.txt")); !os.IsNotExist(err) {
+		t.Errorf("the object is written out of the root: %v", err)
+	}
+}
+
+func TestLocalStoreETagIsContentMD5(t *testing.T) {
+	s := newTestStore(t, "")
+	content := "the quick brown fox"
+	if err := s.Put("a/b.txt", strings.NewReader(content)); err != nil {
+		t.Fatal(err)
+	}
+	info, err := s.Stat("a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum := md5.Sum([]byte(content))
+	if want := strings.ToUpper(hex.EncodeToString(sum[:])); info.ETag != want {
+		t.Errorf("ETag = %s, want %s", info.ETag, want)
+	}
+	if info.Key != "a/b.txt" || info.Size != int64(len(content)) {
+		t.Errorf("key/size = %s/%d, want a/b.txt/%d", info.Key, info.Size, len(content))
+	}
+}
+
+func TestLocalStoreList(t *testing.T) {
+	s := newTestStore(t, "")
+	for _, key := range []string{"icp/2022/09/b.xlsx", "icp/2022/09/a.xlsx", "icp/2022/10/c.xlsx", "audit/d.xlsx"} {
+		if err := s.Put(key, strings.NewReader(key)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	// 写入中的临时文件不列出
+	if err := os.WriteFile(filepath.Join(s.Root, "icp", "2022", "09", "e.xlsx.123456.tmp"), []byte("x"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	objects, err := s.List("icp/2022/09/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var keys []string
+	for _, o := range objects {
+		keys = append(keys, o.Key)
+	}
+	if got, want := strings.Join(keys, ","), "icp/2022/09/a.xlsx,icp/2022/09/b.xlsx"; got != want {
+		t.Errorf("keys = %s, want %s", got, want)
+	}
+
+	all, err := s.List("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 4 || all[0].Key != "audit/d.xlsx" {
+		t.Errorf("all objects = %v, want 4 sorted by key", all)
+	}
+}
+
+func TestLocalStoreSignURL(t *testing.T) {
+	s := newTestStore(t, "http://localhost:8080/files/")
+	u, err := s.SignURL("/icp/a.xlsx", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := "http://localhost:8080/files/icp/a.xlsx"; u != want {
+		t.Errorf("SignURL = %s, want %s", u, want)
+	}
+	if u, _ = s.SignURL("../../icp/a.xlsx", 0); u != "http://localhost:8080/files/icp/a.xlsx" {
+		t.Errorf("SignURL escaped the base URL: %s", u)
+	}
+
+	s.BaseURL = ""
+	u, err = s.SignURL("icp/a.xlsx", 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	parsed, err := url.Parse(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if parsed.Scheme != "file" || filepath.FromSlash(parsed.Path) != filepath.Join(s.Root, "icp", "a.xlsx") {
+		t.Errorf("SignURL = %s, want the file URL under %s", u, s.Root)
+	}
+}
@@ -1,28 +1,140 @@
 package oss
 
-import "github.com/aliyun/aliyun-oss-go-sdk/oss"
+import (
+	"github.com/aliyun/aliyun-oss-go-sdk/oss"
+	"github.com/spf13/viper"
+	"io"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
 
+// Client The Aliyun OSS implementation of ObjectStore, the bucket is connected once and reused
 type Client struct {
 	Endpoint        string
 	AccessKeyId     string
 	AccessKeySecret string
 	BucketName      string
+
+	bucket    *oss.Bucket
+	bucketErr error
+	once      sync.Once
+}
+
+// NewClient Create oss client with the oss.* settings
+func NewClient() *Client {
+	return &Client{
+		Endpoint:        viper.GetString("oss.endpoint"),
+		AccessKeyId:     viper.GetString("oss.access-key"),
+		AccessKeySecret: viper.GetString("oss.access-secret"),
+		BucketName:      viper.GetString("oss.bucket"),
+	}
+}
+
+// getBucket The bucket is created once and shared by all requests of the client
+func (oc *Client) getBucket() (*oss.Bucket, error) {
+	oc.once.Do(func() {
+		client, err := oss.New(oc.Endpoint, oc.AccessKeyId, oc.AccessKeySecret)
+		if err != nil {
+			oc.bucketErr = err
+			return
+		}
+		oc.bucket, oc.bucketErr = client.Bucket(oc.BucketName)
+	})
+	return oc.bucket, oc.bucketErr
 }
 
 // DownloadOssFile Download oss file
 func (oc *Client) DownloadOssFile(object string, savePath string) error {
-	client, err := oss.New(oc.Endpoint, oc.AccessKeyId, oc.AccessKeySecret)
+	return oc.GetToFile(object, savePath)
+}
+
+// ObjectETag The ETag of the oss object, without quotes
+func (oc *Client) ObjectETag(object string) (string, error) {
+	info, err := oc.Stat(object)
 	if err != nil {
-		return err
+		return "", err
+	}
+	return info.ETag, nil
+}
+
+// Get Open the oss object
+func (oc *Client) Get(key string) (io.ReadCloser, error) {
+	bucket, err := oc.getBucket()
+	if err != nil {
+		return nil, err
 	}
-	bucket, err := client.Bucket(oc.BucketName)
+	return bucket.GetObject(key)
+}
+
+// GetToFile Download the oss object to the local file
+func (oc *Client) GetToFile(key string, path string) error {
+	bucket, err := oc.getBucket()
 	if err != nil {
 		return err
 	}
+	return bucket.GetObjectToFile(key, path)
+}
 
-	err = bucket.GetObjectToFile(object, savePath)
+// Put Upload the content as the oss object
+func (oc *Client) Put(key string, r io.Reader) error {
+	bucket, err := oc.getBucket()
 	if err != nil {
 		return err
 	}
-	return nil
+	return bucket.PutObject(key, r)
+}
+
+// Stat The metadata of the oss object
+func (oc *Client) Stat(key string) (*ObjectInfo, error) {
+	bucket, err := oc.getBucket()
+	if err != nil {
+		return nil, err
+	}
+	meta, err := bucket.GetObjectDetailedMeta(key)
+	if err != nil {
+		return nil, err
+	}
+	info := &ObjectInfo{Key: key, ETag: strings.Trim(meta.Get("ETag"), `"`)}
+	info.Size, _ = strconv.ParseInt(meta.Get("Content-Length"), 10, 64)
+	info.LastModified, _ = time.Parse(time.RFC1123, meta.Get("Last-Modified"))
+	return info, nil
+}
+
+// List The oss objects with the key prefix, all pages are listed
+func (oc *Client) List(prefix string) ([]ObjectInfo, error) {
+	bucket, err := oc.getBucket()
+	if err != nil {
+		return nil, err
+	}
+	var objects []ObjectInfo
+	marker := ""
+	for {
+		result, err := bucket.ListObjects(oss.Prefix(prefix), oss.Marker(marker), oss.MaxKeys(1000))
+		if err != nil {
+			return nil, err
+		}
+		for _, o := range result.Objects {
+			objects = append(objects, ObjectInfo{
+				Key:          o.Key,
+				Size:         o.Size,
+				ETag:         strings.Trim(o.ETag, `"`),
+				LastModified: o.LastModified,
+			})
+		}
+		if !result.IsTruncated {
+			return objects, nil
+		}
+		marker = result.NextMarker
+	}
+}
+
+// SignURL The signed URL to get the oss object within the expires
+func (oc *Client) SignURL(key string, expires time.Duration) (string, error) {
+	bucket, err := oc.getBucket()
+	if err != nil {
+		return "", err
+	}
+	return bucket.SignURL(key, oss.HTTPGet, int64(expires/time.Second))
 }
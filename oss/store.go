@@ -0,0 +1,51 @@
+package oss
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"io"
+	"time"
+)
+
+const (
+	// DriverAliyun Objects are stored in the Aliyun OSS bucket
+	DriverAliyun = "aliyun"
+	// DriverLocal Objects are stored in a local directory, exp: a mirror of the bucket or test fixtures
+	DriverLocal = "local"
+)
+
+// ObjectInfo The metadata of an object
+type ObjectInfo struct {
+	Key          string    `json:"key"`
+	Size         int64     `json:"size"`
+	ETag         string    `json:"etag"`
+	LastModified time.Time `json:"last_modified"`
+}
+
+// ObjectStore The storage of the OSS-backed assets, exp: audit screenshots, POD files and the generated artifacts
+type ObjectStore interface {
+	// Get Open the object, the reader must be closed by the caller
+	Get(key string) (io.ReadCloser, error)
+	// GetToFile Download the object to the local file
+	GetToFile(key string, path string) error
+	// Put Upload the content as the object, replace the existing one
+	Put(key string, r io.Reader) error
+	// Stat The metadata of the object
+	Stat(key string) (*ObjectInfo, error)
+	// List The objects with the key prefix
+	List(prefix string) ([]ObjectInfo, error)
+	// SignURL The URL to get the object within the expires
+	SignURL(key string, expires time.Duration) (string, error)
+}
+
+// NewStore Create the object store by oss.driver setting, aliyun (default) or local
+func NewStore() (ObjectStore, error) {
+	switch driver := viper.GetString("oss.driver"); driver {
+	case "", DriverAliyun:
+		return NewClient(), nil
+	case DriverLocal:
+		return NewLocalStore(viper.GetString("oss.local-dir"), viper.GetString("oss.local-base-url"))
+	default:
+		return nil, fmt.Errorf("unknown oss driver %q, want %s or %s", driver, DriverAliyun, DriverLocal)
+	}
+}
@@ -0,0 +1,74 @@
+package oss
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// DefaultUploadPrefix The default key prefix of the uploaded artifacts
+const DefaultUploadPrefix = "icp/{dutyParty}/{yyyy}/{mm}/"
+
+// UploadEnabled Whether the generated artifacts are uploaded to the object store, oss.upload
+func UploadEnabled() bool {
+	return viper.GetBool("oss.upload")
+}
+
+// ArtifactKey The object key of the artifact by the oss.upload-prefix template,
+// placeholders: {dutyParty} {yyyy} {mm}, exp: icp/BE0796544895/2022/09/BE0796544895_202209_01154020.xlsx
+func ArtifactKey(dutyParty string, month time.Time, fileName string) string {
+	viper.SetDefault("oss.upload-prefix", DefaultUploadPrefix)
+	if dutyParty == "" {
+		dutyParty = "ALL"
+	}
+	prefix := strings.NewReplacer(
+		"{dutyParty}", dutyParty,
+		"{yyyy}", month.Format("2006"),
+		"{mm}", month.Format("01"),
+	).Replace(viper.GetString("oss.upload-prefix"))
+	return path.Join(strings.Trim(prefix, "/"), filepath.Base(fileName))
+}
+
+// UploadFile Upload the local file as the object
+func UploadFile(store ObjectStore, key string, localPath string) error {
+	f, err := os.Open(localPath)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return store.Put(key, f)
+}
+
+// UploadArtifact Upload the generated artifact if oss.upload is on, returns the object key, empty if not uploaded
+func UploadArtifact(localPath string, dutyParty string, month time.Time) (string, error) {
+	if !UploadEnabled() {
+		return "", nil
+	}
+	store, err := NewStore()
+	if err != nil {
+		return "", err
+	}
+	key := ArtifactKey(dutyParty, month, localPath)
+	if err = UploadFile(store, key, localPath); err != nil {
+		return "", fmt.Errorf("upload %s as %s failed: %v", localPath, key, err)
+	}
+	return key, nil
+}
+
+// SignedURL The time-limited URL of the object, the expiry is oss.signed-url-expires (default 1h).
+// Returns empty if the key is empty.
+func SignedURL(key string) (string, error) {
+	if key == "" {
+		return "", nil
+	}
+	viper.SetDefault("oss.signed-url-expires", time.Hour)
+	store, err := NewStore()
+	if err != nil {
+		return "", err
+	}
+	return store.SignURL(key, viper.GetDuration("oss.signed-url-expires"))
+}
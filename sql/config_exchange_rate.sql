@@ -0,0 +1,12 @@
+-- 外币对欧元的汇率，按日期保存（如 ECB 的月度汇率）
+CREATE TABLE IF NOT EXISTS config_exchange_rate
+(
+    id         BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    rate_date  DATE            NOT NULL COMMENT 'the rate is used from this date until the next rate of the currency',
+    currency   CHAR(3)         NOT NULL COMMENT 'ISO 4217 code',
+    rate       DECIMAL(20, 6)  NOT NULL COMMENT 'units of the currency per 1 EUR',
+    gmt_create DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    UNIQUE KEY uk_currency_date (currency, rate_date)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='exchange rates against EUR';
@@ -0,0 +1,18 @@
+-- 审计文件的生成记录
+CREATE TABLE IF NOT EXISTS service_audit
+(
+    id                  BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    name                VARCHAR(255)    NOT NULL COMMENT 'audit file name',
+    month               VARCHAR(7)      NOT NULL DEFAULT '' COMMENT 'month of the filter, empty for a date range or customs list',
+    filter              TEXT            NOT NULL COMMENT 'audit filter in JSON',
+    customs_total       INT             NOT NULL DEFAULT 0,
+    row_total           INT             NOT NULL DEFAULT 0,
+    missing_screenshots INT             NOT NULL DEFAULT 0,
+    finding_total       INT             NOT NULL DEFAULT 0,
+    oss_key             VARCHAR(512)    NOT NULL DEFAULT '' COMMENT 'object key of the uploaded audit file',
+    created_at          DATETIME        NOT NULL COMMENT 'UTC',
+    PRIMARY KEY (id),
+    KEY idx_month (month),
+    KEY idx_name (name)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='customs audit runs';
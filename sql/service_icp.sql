@@ -0,0 +1,14 @@
+-- service_icp 的结构变更，按顺序执行
+
+-- ICP 的上一个版本（追加或移除报关单后重新生成的 ICP）
+ALTER TABLE service_icp
+    ADD COLUMN previous_name VARCHAR(128) NOT NULL DEFAULT '' COMMENT 'ICP file which this file is a new version of' AFTER name;
+
+-- ICP 使用的汇率来源
+ALTER TABLE service_icp
+    ADD COLUMN rate_source VARCHAR(255) NOT NULL DEFAULT '' COMMENT 'where the exchange rates used by the ICP come from';
+
+-- 上传到对象存储的 ICP 文件和 vat-note 压缩包，未上传时为空
+ALTER TABLE service_icp
+    ADD COLUMN oss_key          VARCHAR(512) NOT NULL DEFAULT '' COMMENT 'object key of the uploaded ICP file',
+    ADD COLUMN vat_note_oss_key VARCHAR(512) NOT NULL DEFAULT '' COMMENT 'object key of the uploaded vat-note zip';
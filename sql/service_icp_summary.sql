@@ -0,0 +1,17 @@
+-- ICP 的税额汇总，每个 ICP 按总计、税种、目的国、处理代码和运输方式各保存一组
+CREATE TABLE IF NOT EXISTS service_icp_summary
+(
+    id                   BIGINT UNSIGNED NOT NULL AUTO_INCREMENT,
+    icp_name             VARCHAR(128)    NOT NULL COMMENT 'ICP file name',
+    dimension            VARCHAR(32)     NOT NULL COMMENT 'TOTAL, TAX_TYPE, COUNTRY, PROCESS_CODE or MODE',
+    dimension_key        VARCHAR(64)     NOT NULL DEFAULT '' COMMENT 'value of the dimension, ALL for TOTAL',
+    customs_total        INT             NOT NULL DEFAULT 0,
+    mrn_total            INT             NOT NULL DEFAULT 0,
+    item_total           INT             NOT NULL DEFAULT 0,
+    local_currency_value DECIMAL(20, 6)  NOT NULL DEFAULT 0 COMMENT 'declared value, counted once per item',
+    import_duty          DECIMAL(20, 6)  NOT NULL DEFAULT 0,
+    gmt_create           DATETIME        NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (id),
+    KEY idx_icp_name (icp_name)
+) ENGINE = InnoDB
+  DEFAULT CHARSET = utf8mb4 COMMENT ='tax totals reconciliation of ICP files';
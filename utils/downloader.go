@@ -0,0 +1,218 @@
+package utils
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// DownloadTask The file to download
+type DownloadTask struct {
+	// Key The business key of the file, exp: customs ID
+	Key string `json:"key"`
+	// Kind The kind of the file, exp: vatNote
+	Kind     string `json:"kind"`
+	Uri      string `json:"uri"`
+	SavePath string `json:"-"`
+}
+
+// DownloadResult The result of the download task
+type DownloadResult struct {
+	DownloadTask
+	Attempts int    `json:"attempts"`
+	Error    string `json:"error,omitempty"`
+}
+
+// Failed Whether the download task failed
+func (r DownloadResult) Failed() bool {
+	return r.Error != ""
+}
+
+// StatusError The response status is not 200 OK
+type StatusError struct {
+	Uri        string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("download %s got status %d", e.Uri, e.StatusCode)
+}
+
+// retryable Whether it is worth retrying the request, client errors are not retried except 408 and 429
+func (e *StatusError) retryable() bool {
+	return e.StatusCode >= 500 || e.StatusCode == http.StatusTooManyRequests || e.StatusCode == http.StatusRequestTimeout
+}
+
+// ContentTypeError The content type of the response is not accepted
+type ContentTypeError struct {
+	ContentType string
+	Accepted    []string
+}
+
+func (e *ContentTypeError) Error() string {
+	return fmt.Sprintf("unexpected content type %q, want %v", e.ContentType, e.Accepted)
+}
+
+// ValidationError The downloaded file is rejected by Downloader.Validate, exp: a truncated PDF
+type ValidationError struct {
+	Uri string
+	Err error
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("invalid file downloaded from %s: %v", e.Uri, e.Err)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return e.Err
+}
+
+// retryable Whether the failed download is worth retrying, the same content is expected if it is downloaded again
+// when the content type or the file is rejected
+func retryable(err error) bool {
+	switch e := err.(type) {
+	case *StatusError:
+		return e.retryable()
+	case *ContentTypeError, *ValidationError:
+		return false
+	}
+	return true
+}
+
+// Downloader Download files with a bounded worker pool, every request has a timeout and is retried with backoff
+type Downloader struct {
+	// Workers The number of files downloaded at the same time
+	Workers int
+	// Timeout The timeout of every request
+	Timeout time.Duration
+	// Retries The retry times after the first attempt failed
+	Retries int
+	// Backoff The wait time before the first retry, doubled for every next retry
+	Backoff time.Duration
+	// ContentTypes The accepted media types of the response, exp: application/pdf. Empty accepts all.
+	ContentTypes []string
+	// Validate Check the downloaded file before it is saved, nil skips the check
+	Validate func(path string) error
+
+	client *http.Client
+	once   sync.Once
+}
+
+// httpClient The http client shared by all requests of the downloader
+func (d *Downloader) httpClient() *http.Client {
+	d.once.Do(func() {
+		d.client = &http.Client{Timeout: d.Timeout}
+	})
+	return d.client
+}
+
+// Download Download all tasks, the results are in the same order as the tasks
+func (d *Downloader) Download(tasks []DownloadTask) []DownloadResult {
+	workers := d.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+
+	results := make([]DownloadResult, len(tasks))
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = d.DownloadOne(tasks[i])
+			}
+		}()
+	}
+	for i := range tasks {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+
+	return results
+}
+
+// DownloadOne Download the task, retry with backoff if failed
+func (d *Downloader) DownloadOne(task DownloadTask) DownloadResult {
+	result := DownloadResult{DownloadTask: task}
+	backoff := d.Backoff
+	for {
+		result.Attempts++
+		err := d.fetch(task.Uri, task.SavePath)
+		if err == nil {
+			result.Error = ""
+			return result
+		}
+		result.Error = err.Error()
+		log.Printf("Download %s(%s) attempt %d failed: %v\n", task.Key, task.Kind, result.Attempts, err)
+
+		if !retryable(err) || result.Attempts > d.Retries {
+			return result
+		}
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// fetch Request the uri and save the body to the path.
+// The body is written into a temporary file first, so no broken file is left if the download failed.
+func (d *Downloader) fetch(uri string, savePath string) error {
+	res, err := d.httpClient().Get(uri)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode != http.StatusOK {
+		return &StatusError{Uri: uri, StatusCode: res.StatusCode}
+	}
+	if err = d.checkContentType(res.Header.Get("Content-Type")); err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(savePath), filepath.Base(savePath)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	_, err = io.Copy(tmp, res.Body)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return err
+	}
+	if d.Validate != nil {
+		if err = d.Validate(tmp.Name()); err != nil {
+			return &ValidationError{Uri: uri, Err: err}
+		}
+	}
+	return os.Rename(tmp.Name(), savePath)
+}
+
+// checkContentType Whether the content type of response is accepted
+func (d *Downloader) checkContentType(contentType string) error {
+	if len(d.ContentTypes) == 0 {
+		return nil
+	}
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return &ContentTypeError{ContentType: contentType, Accepted: d.ContentTypes}
+	}
+	for _, ct := range d.ContentTypes {
+		if strings.EqualFold(ct, mediaType) {
+			return nil
+		}
+	}
+	return &ContentTypeError{ContentType: mediaType, Accepted: d.ContentTypes}
+}
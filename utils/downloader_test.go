@@ -0,0 +1,60 @@
+package utils
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestDownloadOneRetry(t *testing.T) {
+	tests := []struct {
+		name         string
+		status       int
+		contentType  string
+		validate     func(path string) error
+		wantAttempts int32
+		wantFailed   bool
+	}{
+		{name: "success", status: http.StatusOK, contentType: "application/pdf", wantAttempts: 1},
+		{name: "server error is retried", status: http.StatusBadGateway, contentType: "application/pdf", wantAttempts: 3, wantFailed: true},
+		{name: "not found is not retried", status: http.StatusNotFound, contentType: "application/pdf", wantAttempts: 1, wantFailed: true},
+		{name: "content type is not retried", status: http.StatusOK, contentType: "text/html", wantAttempts: 1, wantFailed: true},
+		{
+			name:         "validation failure is not retried",
+			status:       http.StatusOK,
+			contentType:  "application/pdf",
+			validate:     func(path string) error { return errors.New("truncated pdf") },
+			wantAttempts: 1,
+			wantFailed:   true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var requests int32
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				atomic.AddInt32(&requests, 1)
+				w.Header().Set("Content-Type", tt.contentType)
+				w.WriteHeader(tt.status)
+				_, _ = w.Write([]byte("%PDF-1.4"))
+			}))
+			defer server.Close()
+
+			d := &Downloader{Retries: 2, ContentTypes: []string{"application/pdf"}, Validate: tt.validate}
+			savePath := filepath.Join(t.TempDir(), "file.pdf")
+			result := d.DownloadOne(DownloadTask{Key: "C1", Kind: "test", Uri: server.URL, SavePath: savePath})
+
+			if result.Failed() != tt.wantFailed {
+				t.Errorf("Failed() = %v, want %v, error: %s", result.Failed(), tt.wantFailed, result.Error)
+			}
+			if requests != tt.wantAttempts || int32(result.Attempts) != tt.wantAttempts {
+				t.Errorf("requests = %d, attempts = %d, want %d", requests, result.Attempts, tt.wantAttempts)
+			}
+			if IsExists(savePath) == tt.wantFailed {
+				t.Errorf("file saved = %v, want %v", IsExists(savePath), !tt.wantFailed)
+			}
+		})
+	}
+}
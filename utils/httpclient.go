@@ -1,22 +1,14 @@
 package utils
 
 import (
-	"io"
-	"net/http"
-	"os"
+	"time"
 )
 
+// DownloadTimeout The default timeout of downloading a file
+const DownloadTimeout = 60 * time.Second
+
 // DownloadFileTo Download file and save to local file
 func DownloadFileTo(uri string, savePath string) (err error) {
-	res, err := http.Get(uri)
-	if err != nil {
-		return err
-	}
-	f, err := os.Create(savePath)
-	if err != nil {
-		return err
-	}
-	_, err = io.Copy(f, res.Body)
-
-	return err
+	d := &Downloader{Timeout: DownloadTimeout}
+	return d.fetch(uri, savePath)
 }
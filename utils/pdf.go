@@ -0,0 +1,111 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strconv"
+)
+
+var (
+	pdfPagePattern   = regexp.MustCompile(`/Type\s*/Page\b`)
+	pdfCountPattern  = regexp.MustCompile(`/Type\s*/Pages\b[^>]*?/Count\s+(\d+)|/Count\s+(\d+)[^>]*?/Type\s*/Pages\b`)
+	pdfStreamPattern = regexp.MustCompile(`stream\r?\n`)
+	htmlPattern      = regexp.MustCompile(`(?i)<\s*(!doctype\s+html|html|head|body)\b`)
+)
+
+// VerifyPDF Check the file is a complete PDF document and returns its page count.
+// The file must be at least minSize bytes, start with the PDF header, not be an HTML page, and have at least one page.
+func VerifyPDF(path string, minSize int64) (int, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return 0, err
+	}
+	if info.Size() < minSize {
+		return 0, fmt.Errorf("%s is too small: %d bytes, want at least %d", path, info.Size(), minSize)
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return 0, err
+	}
+
+	head := content
+	if len(head) > 1024 {
+		head = head[:1024]
+	}
+	if htmlPattern.Match(head) {
+		return 0, fmt.Errorf("%s is an HTML page, not a PDF", path)
+	}
+	// PDF 头允许出现在前1024个字节内
+	if !bytes.Contains(head, []byte("%PDF-")) {
+		return 0, fmt.Errorf("%s has no PDF header", path)
+	}
+
+	tail := content
+	if len(tail) > 1024 {
+		tail = tail[len(tail)-1024:]
+	}
+	if !bytes.Contains(tail, []byte("%%EOF")) {
+		return 0, fmt.Errorf("%s is truncated, no %%%%EOF marker", path)
+	}
+
+	pages := countPDFPages(content)
+	if pages == 0 {
+		return 0, fmt.Errorf("%s has no page", path)
+	}
+	return pages, nil
+}
+
+// countPDFPages Count the pages of the PDF content.
+// The page objects are counted first, the /Count of the page tree is used if the pages are in compressed object streams.
+func countPDFPages(content []byte) int {
+	if pages := len(pdfPagePattern.FindAll(content, -1)); pages > 0 {
+		return pages
+	}
+	if pages := pageTreeCount(content); pages > 0 {
+		return pages
+	}
+
+	// PDF 1.5+ 的对象可能在压缩的对象流中
+	treeCount, pageObjects := 0, 0
+	for _, loc := range pdfStreamPattern.FindAllIndex(content, -1) {
+		start := loc[1]
+		end := bytes.Index(content[start:], []byte("endstream"))
+		if end < 0 {
+			break
+		}
+		r, err := zlib.NewReader(bytes.NewReader(content[start : start+end]))
+		if err != nil {
+			continue
+		}
+		data, _ := io.ReadAll(r)
+		_ = r.Close()
+		if n := pageTreeCount(data); n > treeCount {
+			treeCount = n
+		}
+		pageObjects += len(pdfPagePattern.FindAll(data, -1))
+	}
+	if treeCount > 0 {
+		return treeCount
+	}
+	return pageObjects
+}
+
+// pageTreeCount The largest /Count of the page tree nodes, which is the page count of the root node
+func pageTreeCount(content []byte) int {
+	count := 0
+	for _, m := range pdfCountPattern.FindAllSubmatch(content, -1) {
+		v := m[1]
+		if len(v) == 0 {
+			v = m[2]
+		}
+		if n, err := strconv.Atoi(string(v)); err == nil && n > count {
+			count = n
+		}
+	}
+	return count
+}
@@ -0,0 +1,131 @@
+package utils
+
+import (
+	"bytes"
+	"compress/zlib"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// plainPDF A PDF whose page objects are not compressed
+func plainPDF(pages int) string {
+	var b strings.Builder
+	b.WriteString("%PDF-1.4\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	b.WriteString("2 0 obj\n<< /Type /Pages /Kids [] /Count 0 >>\nendobj\n")
+	for i := 0; i < pages; i++ {
+		b.WriteString("3 0 obj\n<< /Type /Page /Parent 2 0 R /MediaBox [0 0 595 842] >>\nendobj\n")
+	}
+	b.WriteString("trailer\n<< /Root 1 0 R >>\n%%EOF\n")
+	return b.String()
+}
+
+// objectStreamPDF A PDF 1.5 file whose page tree and pages are in a compressed object stream
+func objectStreamPDF(t *testing.T, pages int) string {
+	var objects strings.Builder
+	objects.WriteString("<< /Type /Pages /Kids [4 0 R 5 0 R 6 0 R] /Count 3 >>\n")
+	for i := 0; i < pages; i++ {
+		objects.WriteString("<< /Type /Page /Parent 2 0 R >>\n")
+	}
+	var compressed bytes.Buffer
+	w, err := zlib.NewWriterLevel(&compressed, zlib.BestCompression)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = w.Write([]byte(objects.String())); err != nil {
+		t.Fatal(err)
+	}
+	if err = w.Close(); err != nil {
+		t.Fatal(err)
+	}
+	// 确认页对象只在压缩流中
+	if bytes.Contains(compressed.Bytes(), []byte("/Type")) {
+		t.Fatal("the object stream is not compressed")
+	}
+
+	var b strings.Builder
+	b.WriteString("%PDF-1.5\n1 0 obj\n<< /Type /Catalog /Pages 2 0 R >>\nendobj\n")
+	b.WriteString("3 0 obj\n<< /Type /ObjStm /N 4 /First 20 /Filter /FlateDecode >>\nstream\n")
+	b.Write(compressed.Bytes())
+	b.WriteString("\nendstream\nendobj\nstartxref\n0\n%%EOF\n")
+	return b.String()
+}
+
+func TestVerifyPDF(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		minSize int64
+		pages   int
+		err     string
+	}{
+		{
+			name:    "html error page",
+			content: "<!DOCTYPE html><html><body>502 Bad Gateway %PDF-</body></html>",
+			err:     "HTML page",
+		},
+		{
+			name:    "below min size",
+			content: plainPDF(1),
+			minSize: 10240,
+			err:     "too small",
+		},
+		{
+			name:    "no header",
+			content: "just some text\n%%EOF\n",
+			err:     "no PDF header",
+		},
+		{
+			name:    "truncated",
+			content: strings.TrimSuffix(plainPDF(1), "%%EOF\n"),
+			err:     "truncated",
+		},
+		{
+			name:    "no page",
+			content: plainPDF(0),
+			err:     "has no page",
+		},
+		{
+			name:    "plain pages",
+			content: plainPDF(2),
+			pages:   2,
+		},
+		{
+			name:    "compressed object stream",
+			content: objectStreamPDF(t, 3),
+			pages:   3,
+		},
+	}
+
+	dir := t.TempDir()
+	for i, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(dir, string(rune('a'+i))+".pdf")
+			if err := os.WriteFile(path, []byte(tt.content), 0644); err != nil {
+				t.Fatal(err)
+			}
+			pages, err := VerifyPDF(path, tt.minSize)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("error = %v, want containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if pages != tt.pages {
+				t.Errorf("pages = %d, want %d", pages, tt.pages)
+			}
+		})
+	}
+}
+
+func TestCountPDFPagesPrefersPageTreeInObjectStream(t *testing.T) {
+	// 页树的 /Count 比对象流中能找到的页对象多时，以 /Count 为准
+	content := objectStreamPDF(t, 1)
+	if pages := countPDFPages([]byte(content)); pages != 3 {
+		t.Errorf("pages = %d, want 3", pages)
+	}
+}
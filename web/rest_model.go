@@ -5,6 +5,9 @@ import "github.com/go-playground/validator"
 const (
 	SUCCESS = "success"
 	FAIL    = "fail"
+
+	// SignedUrlHeader The response header of the signed URL when the file itself is responded
+	SignedUrlHeader = "X-Signed-Url"
 )
 
 type (
@@ -13,13 +16,38 @@ type (
 		CustomsIds []string `json:"customs_ids" validate:"required"`
 	}
 
+	CustomsRemoveFromICP struct {
+		FileName   string   `json:"file_name" validate:"required"`
+		CustomsIds []string `json:"customs_ids" validate:"required"`
+	}
+
+	CustomsOfVatNote struct {
+		CustomsIds []string `json:"customs_ids"`
+	}
+
+	AuditFilter struct {
+		// Month exp: 2006-01
+		Month string `json:"month"`
+		// From, To exp: 2006-01-02, both are included
+		From       string   `json:"from"`
+		To         string   `json:"to"`
+		DutyParty  string   `json:"duty_party"`
+		CustomsIds []string `json:"customs_ids"`
+	}
+
 	CustomValidator struct {
 		Validator *validator.Validate
 	}
 
 	IcpResponse struct {
-		Status   string   `json:"status"`
-		FileName string   `json:"file_name"`
-		Errors   []string `json:"errors"`
+		Status           string   `json:"status"`
+		FileName         string   `json:"file_name"`
+		PreviousFileName string   `json:"previous_file_name,omitempty"`
+		Url              string   `json:"url,omitempty"`
+		VatNoteUrl       string   `json:"vat_note_url,omitempty"`
+		Added            []string `json:"added,omitempty"`
+		Existing         []string `json:"existing,omitempty"`
+		Removed          []string `json:"removed,omitempty"`
+		Errors           []string `json:"errors"`
 	}
 )
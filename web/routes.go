@@ -7,8 +7,11 @@ import (
 	"log"
 	"net/http"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"sysafari.com/customs/tguard/audit"
 	icp2 "sysafari.com/customs/tguard/icp"
+	"sysafari.com/customs/tguard/oss"
 	"sysafari.com/customs/tguard/utils"
 	"time"
 )
@@ -23,6 +26,7 @@ func (cv *CustomValidator) Validate(i interface{}) error {
 
 // AppendToICP
 // @Summary      Add the specified Customs IDs to the specified ICP file
+// @Description  The customs already in the ICP file are kept and a new version of the ICP file is generated.
 // @Description  If the specified ICP file does not exist will create a new ICP file with the specified ICP file name
 // @Tags         icp
 // @Accept       json
@@ -48,12 +52,7 @@ func AppendToICP(c echo.Context) (err error) {
 		})
 	}
 
-	icp := &icp2.FileOfICP{
-		FileName:   aicp.FileName,
-		CustomsIDs: aicp.CustomsIds,
-	}
-
-	filename := icp.GenerateICP()
+	icp := icp2.AppendToICP(aicp.FileName, aicp.CustomsIds)
 	errs = icp.Errors
 
 	if errs != nil && len(errs) > 0 {
@@ -64,8 +63,56 @@ func AppendToICP(c echo.Context) (err error) {
 	}
 
 	return c.JSON(http.StatusOK, &IcpResponse{
-		Status:   SUCCESS,
-		FileName: filename,
+		Status:           SUCCESS,
+		FileName:         icp.FileName,
+		PreviousFileName: icp.PreviousFileName,
+		Url:              signedURL(icp.OssKey),
+		Added:            icp.AddedCustomsIDs,
+		Existing:         icp.ExistingCustomsIDs,
+	})
+}
+
+// RemoveFromICP
+// @Summary      Remove the specified Customs IDs from the specified ICP file
+// @Description  The ICP file will be regenerated without the customs as a new version, the specified ICP file is kept
+// @Tags         icp
+// @Accept       json
+// @Produce      json
+// @Param 		 message body CustomsRemoveFromICP true "Customs remove from the ICP file"
+// @Success      200
+// @Failure      400
+// @Router       /icp/remove [post]
+func RemoveFromICP(c echo.Context) (err error) {
+	var errs []string
+	ricp := new(CustomsRemoveFromICP)
+	if err = c.Bind(ricp); err != nil {
+		errs = append(errs, err.Error())
+	}
+	if err = c.Validate(ricp); err != nil {
+		errs = append(errs, err.Error())
+	}
+
+	if len(errs) > 0 {
+		return c.JSON(http.StatusBadRequest, &IcpResponse{
+			Status: FAIL,
+			Errors: errs,
+		})
+	}
+
+	icp := icp2.RemoveFromICP(ricp.FileName, ricp.CustomsIds)
+	if len(icp.Errors) > 0 {
+		return c.JSON(http.StatusInternalServerError, &IcpResponse{
+			Status: FAIL,
+			Errors: icp.Errors,
+		})
+	}
+
+	return c.JSON(http.StatusOK, &IcpResponse{
+		Status:           SUCCESS,
+		FileName:         icp.FileName,
+		PreviousFileName: icp.PreviousFileName,
+		Url:              signedURL(icp.OssKey),
+		Removed:          icp.RemovedCustomsIDs,
 	})
 }
 
@@ -81,7 +128,6 @@ func AppendToICP(c echo.Context) (err error) {
 // @Failure      400
 // @Router       /icp/taxAgency/{dutyParty} [get]
 func MakeICPForTaxAgency(c echo.Context) (err error) {
-	var errs []string
 	dutyParty := c.Param("dutyParty")
 	if dutyParty == "" {
 		return c.JSON(http.StatusBadRequest, &IcpResponse{
@@ -105,12 +151,12 @@ func MakeICPForTaxAgency(c echo.Context) (err error) {
 	start := time.Now().UnixMilli()
 
 	// Make ICP
-	filename, errs := icp2.MakeICPForDutyPart(dutyParty, month)
+	icp := icp2.MakeICPForDutyPart(dutyParty, month)
 
-	if errs != nil && len(errs) > 0 {
+	if len(icp.Errors) > 0 {
 		return c.JSON(http.StatusInternalServerError, &IcpResponse{
 			Status: FAIL,
-			Errors: errs,
+			Errors: icp.Errors,
 		})
 	}
 	end := time.Now().UnixMilli()
@@ -118,8 +164,10 @@ func MakeICPForTaxAgency(c echo.Context) (err error) {
 	log.Printf("**** Generat ICP time costs: %d ms****\n", end-start)
 
 	return c.JSON(http.StatusOK, &IcpResponse{
-		Status:   SUCCESS,
-		FileName: filename,
+		Status:     SUCCESS,
+		FileName:   icp.FileName,
+		Url:        signedURL(icp.OssKey),
+		VatNoteUrl: signedURL(icp.VatNoteOssKey),
 	})
 }
 
@@ -159,3 +207,309 @@ func DownloadFile(c echo.Context) error {
 
 	return c.Attachment(icpPath, filename)
 }
+
+// MakeVatNote
+// @Summary      Generate the vat-note zip of the duty party
+// @Description  The vat notes and transfer docs of the customs are downloaded and compressed into a zip.
+// @Description  If no customs IDs are posted, all customs of the duty party in the month are used.
+// @Tags         vatnote
+// @Accept       json
+// @Produce      json
+// @Param 		 dutyParty path string true "The duty party of tax agency"
+// @Param 		 month query string false "which month, default is this month,example:2006-01"
+// @Param 		 message body CustomsOfVatNote false "The customs IDs put into the vat-note zip"
+// @Success      200
+// @Failure      400
+// @Router       /vatnote/{dutyParty} [post]
+func MakeVatNote(c echo.Context) (err error) {
+	dutyParty := c.Param("dutyParty")
+	if dutyParty == "" {
+		return c.JSON(http.StatusBadRequest, &IcpResponse{
+			Status: FAIL,
+			Errors: []string{fmt.Sprintf("The duty party is required.")},
+		})
+	}
+	month := c.QueryParam("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+		log.Printf("Month is empty, use this month:%s instead.\n", month)
+	}
+	if _, err = time.Parse("2006-01", month); err != nil {
+		return c.JSON(http.StatusBadRequest, &IcpResponse{
+			Status: FAIL,
+			Errors: []string{fmt.Sprintf("The month:%s invalid format(correct: 2006-01).", month)},
+		})
+	}
+
+	customs := new(CustomsOfVatNote)
+	if err = c.Bind(customs); err != nil {
+		return c.JSON(http.StatusBadRequest, &IcpResponse{
+			Status: FAIL,
+			Errors: []string{err.Error()},
+		})
+	}
+
+	vatNote := icp2.MakeVatNoteForDutyParty(dutyParty, month, customs.CustomsIds)
+	if len(vatNote.Errors) > 0 {
+		return c.JSON(http.StatusInternalServerError, &IcpResponse{
+			Status: FAIL,
+			Errors: vatNote.Errors,
+		})
+	}
+
+	return c.JSON(http.StatusOK, &IcpResponse{
+		Status:   SUCCESS,
+		FileName: vatNote.VatNoteZipFileName,
+		Url:      signedURL(vatNote.VatNoteOssKey),
+	})
+}
+
+// DownloadVatNote
+// Download vat-note zip
+// @Summary      Download vat-note zip
+// @Description  File name format (2022-09-BE0796544895-vatnote.zip), the file path will be found by the date in the file name
+// @Tags         download
+// @Accept       json
+// @Produce      json
+// @Param        name   path      string  true  "vat-note zip filename, exp: 2022-09-BE0796544895-vatnote.zip"
+// @Success      200
+// @Failure      400
+// @Router       /vatnote/download/{name} [get]
+func DownloadVatNote(c echo.Context) error {
+	name := c.Param("name")
+	if name == "" {
+		return c.String(http.StatusBadRequest, fmt.Sprintf("The filename must be provided,but was empty."))
+	}
+	zipPath, err := icp2.VatNoteZipFilePath(name)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if !utils.IsExists(zipPath) {
+		log.Printf("The vat-note zip: %s not found.\n", zipPath)
+		return c.String(http.StatusNotFound, fmt.Sprintf("The vat-note zip:%s not found.", name))
+	}
+
+	return c.Attachment(zipPath, name)
+}
+
+// MakeSubmissionPackage
+// @Summary      Bundle the ICP file with its supporting documents into a submission package
+// @Description  The tax receipts, POD files and vat notes of the ICP are downloaded, the workbook links are rewritten to relative paths inside the package
+// @Tags         icp
+// @Accept       json
+// @Produce      json
+// @Param        filename   path      string  true  "ICP filename, exp: BE0796544895_202209_01154020.xlsx"
+// @Success      200
+// @Failure      400
+// @Router       /icp/package/{filename} [post]
+func MakeSubmissionPackage(c echo.Context) error {
+	filename := c.Param("filename")
+	if filename == "" {
+		return c.JSON(http.StatusBadRequest, &IcpResponse{
+			Status: FAIL,
+			Errors: []string{fmt.Sprintf("The filename must be provided,but was empty.")},
+		})
+	}
+
+	pkg := icp2.MakeSubmissionPackage(filename)
+	if len(pkg.Errors) > 0 {
+		return c.JSON(http.StatusInternalServerError, &IcpResponse{
+			Status: FAIL,
+			Errors: pkg.Errors,
+		})
+	}
+
+	return c.JSON(http.StatusOK, &IcpResponse{
+		Status:   SUCCESS,
+		FileName: pkg.FileName,
+	})
+}
+
+// DownloadSubmissionPackage
+// Download submission package
+// @Summary      Download the submission package of ICP
+// @Description  File name format (BE0796544895_202209_01154020-package.zip), the file path will be found by the date in the file name
+// @Tags         download
+// @Accept       json
+// @Produce      json
+// @Param        name   path      string  true  "package filename, exp: BE0796544895_202209_01154020-package.zip"
+// @Success      200
+// @Failure      400
+// @Router       /icp/package/download/{name} [get]
+func DownloadSubmissionPackage(c echo.Context) error {
+	name := c.Param("name")
+	packagePath, err := icp2.PackageFilePath(name)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if !utils.IsExists(packagePath) {
+		log.Printf("The package: %s not found.\n", packagePath)
+		return c.String(http.StatusNotFound, fmt.Sprintf("The package:%s not found.", name))
+	}
+
+	return c.Attachment(packagePath, name)
+}
+
+// MakeCompletenessReport
+// @Summary      Check the POD and tax receipt of the customs in the month
+// @Description  Lists the customs without POD, the tracking numbers without logistics info and the customs with only TMP_TAX or without any tax process.
+// @Description  The report is also saved as xlsx and JSON, which can be downloaded by the file name.
+// @Tags         completeness
+// @Accept       json
+// @Produce      json
+// @Param 		 month query string false "which month, default is this month,example:2006-01"
+// @Param 		 dutyParty query string false "The duty party of tax agency, all duty parties if empty"
+// @Success      200
+// @Failure      400
+// @Router       /completeness [get]
+func MakeCompletenessReport(c echo.Context) error {
+	month := c.QueryParam("month")
+	if month == "" {
+		month = time.Now().Format("2006-01")
+		log.Printf("Month is empty, use this month:%s instead.\n", month)
+	}
+	if _, err := time.Parse("2006-01", month); err != nil {
+		return c.JSON(http.StatusBadRequest, &IcpResponse{
+			Status: FAIL,
+			Errors: []string{fmt.Sprintf("The month:%s invalid format(correct: 2006-01).", month)},
+		})
+	}
+
+	report := icp2.MakeCompletenessReport(month, c.QueryParam("dutyParty"))
+	return c.JSON(http.StatusOK, report)
+}
+
+// DownloadCompletenessReport
+// @Summary      Download the completeness report
+// @Description  File name format (2024-09-BE0796544895-completeness.xlsx or .json), the file path will be found by the month in the file name
+// @Tags         download
+// @Accept       json
+// @Produce      json
+// @Param        name   path      string  true  "report filename, exp: 2024-09-BE0796544895-completeness.xlsx"
+// @Success      200
+// @Failure      400
+// @Router       /completeness/download/{name} [get]
+func DownloadCompletenessReport(c echo.Context) error {
+	name := c.Param("name")
+	reportPath, err := icp2.CompletenessFilePath(name)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if !utils.IsExists(reportPath) {
+		log.Printf("The completeness report: %s not found.\n", reportPath)
+		return c.String(http.StatusNotFound, fmt.Sprintf("The completeness report:%s not found.", name))
+	}
+
+	return c.Attachment(reportPath, name)
+}
+
+// MakeAudit
+// @Summary      Generate the customs audit file by the filter and download it
+// @Description  The submitted customs are filtered by the month or the from/to dates, the duty party and the customs IDs.
+// @Description  The file name is derived from the filter, exp: BE0796544895_2022-09.xlsx
+// @Description  If the file is uploaded to OSS, the signed URL is in the X-Signed-Url header
+// @Tags         audit
+// @Accept       json
+// @Produce      json
+// @Param 		 message body AuditFilter true "The filter of the customs to audit"
+// @Success      200
+// @Failure      400
+// @Router       /audit [post]
+func MakeAudit(c echo.Context) (err error) {
+	filter := new(AuditFilter)
+	if err = c.Bind(filter); err != nil {
+		return c.JSON(http.StatusBadRequest, &IcpResponse{
+			Status: FAIL,
+			Errors: []string{err.Error()},
+		})
+	}
+
+	customsAudit := &audit.CustomsAudit{
+		Month:      filter.Month,
+		From:       filter.From,
+		To:         filter.To,
+		DutyParty:  filter.DutyParty,
+		CustomsIds: filter.CustomsIds,
+	}
+	if err = customsAudit.Validate(); err != nil {
+		return c.JSON(http.StatusBadRequest, &IcpResponse{
+			Status: FAIL,
+			Errors: []string{err.Error()},
+		})
+	}
+
+	customsAudit.MakeAudit()
+	if len(customsAudit.Errors) > 0 {
+		return c.JSON(http.StatusInternalServerError, &IcpResponse{
+			Status:   FAIL,
+			FileName: customsAudit.FileName,
+			Errors:   customsAudit.Errors,
+		})
+	}
+
+	if url := signedURL(customsAudit.OssKey); url != "" {
+		c.Response().Header().Set(SignedUrlHeader, url)
+	}
+	return c.Attachment(customsAudit.FilePath, customsAudit.FileName)
+}
+
+// AuditFiles
+// @Summary      List the latest audit files
+// @Description  The audit runs are listed from the newest, with the filter, row count, missing screenshots and findings
+// @Description  The uploaded audit files have the signed URL
+// @Tags         audit
+// @Accept       json
+// @Produce      json
+// @Param 		 month query string false "which month, example:2006-01"
+// @Param 		 limit query int false "the max count, default is 50"
+// @Success      200
+// @Failure      500
+// @Router       /audit/files [get]
+func AuditFiles(c echo.Context) error {
+	limit, _ := strconv.Atoi(c.QueryParam("limit"))
+	audits, err := audit.QueryAuditFiles(c.QueryParam("month"), limit)
+	if err != nil {
+		return c.JSON(http.StatusInternalServerError, &IcpResponse{
+			Status: FAIL,
+			Errors: []string{err.Error()},
+		})
+	}
+	for i := range audits {
+		audits[i].Url = signedURL(audits[i].OssKey)
+	}
+	return c.JSON(http.StatusOK, audits)
+}
+
+// DownloadAudit
+// @Summary      Download the audit file
+// @Description  File name format (BE0796544895_2022-09_20220930150405.xlsx), the file is found in the audit save directory
+// @Tags         download
+// @Accept       json
+// @Produce      json
+// @Param        name   path      string  true  "audit filename, exp: 2022-09_20220930150405.xlsx"
+// @Success      200
+// @Failure      400
+// @Router       /audit/download/{name} [get]
+func DownloadAudit(c echo.Context) error {
+	name := c.Param("name")
+	auditPath, err := audit.AuditFilePath(name)
+	if err != nil {
+		return c.String(http.StatusBadRequest, err.Error())
+	}
+	if !utils.IsExists(auditPath) {
+		log.Printf("The audit: %s not found.\n", auditPath)
+		return c.String(http.StatusNotFound, fmt.Sprintf("The audit:%s not found.", name))
+	}
+
+	return c.Attachment(auditPath, name)
+}
+
+// signedURL The signed URL of the uploaded object, empty if the object is not uploaded or signing failed
+func signedURL(key string) string {
+	url, err := oss.SignedURL(key)
+	if err != nil {
+		log.Printf("Sign the URL of %s failed: %v\n", key, err)
+		return ""
+	}
+	return url
+}
@@ -0,0 +1,179 @@
+package workbook
+
+import (
+	"fmt"
+	"github.com/spf13/viper"
+	"github.com/xuri/excelize/v2"
+	"strings"
+	"unicode/utf8"
+)
+
+const (
+	// DefaultCurrencyNumFmt The default number format of the amount cells
+	DefaultCurrencyNumFmt = "#,##0.00"
+	// DefaultWeightNumFmt The default number format of the weight cells
+	DefaultWeightNumFmt = "#,##0.000"
+	// DefaultRateNumFmt The default number format of the exchange rate cells
+	DefaultRateNumFmt = "0.0000##"
+)
+
+// Profile The style profile shared by all generated workbooks (ICP, VAT ICP, audit, reports)
+type Profile struct {
+	// HeaderBold Bold the header row
+	HeaderBold bool
+	// HeaderFill The fill color of the header row, exp: #D9E1F2. Empty is no fill.
+	HeaderFill string
+	// FreezeHeader Freeze the header row, so it is always visible when scrolling
+	FreezeHeader bool
+	// AutoFilter Add the auto filter on the header row
+	AutoFilter bool
+	// Hyperlinks Write the links as clickable hyperlinks, otherwise as plain strings
+	Hyperlinks bool
+	// MinWidth, MaxWidth The range of the column width sized by the content
+	MinWidth float64
+	MaxWidth float64
+	// ColumnWidths The fixed width of the columns by header name, the key is lower case
+	ColumnWidths map[string]float64
+
+	CurrencyNumFmt string
+	WeightNumFmt   string
+	RateNumFmt     string
+}
+
+// LoadProfile Load the workbook style profile from the workbook.* settings
+func LoadProfile() *Profile {
+	viper.SetDefault("workbook.header-bold", true)
+	viper.SetDefault("workbook.header-fill", "#D9E1F2")
+	viper.SetDefault("workbook.freeze-header", true)
+	viper.SetDefault("workbook.auto-filter", true)
+	viper.SetDefault("workbook.hyperlinks", true)
+	viper.SetDefault("workbook.min-width", 8)
+	viper.SetDefault("workbook.max-width", 60)
+	viper.SetDefault("workbook.currency-num-fmt", DefaultCurrencyNumFmt)
+	viper.SetDefault("workbook.weight-num-fmt", DefaultWeightNumFmt)
+	viper.SetDefault("workbook.rate-num-fmt", DefaultRateNumFmt)
+
+	p := &Profile{
+		HeaderBold:     viper.GetBool("workbook.header-bold"),
+		HeaderFill:     viper.GetString("workbook.header-fill"),
+		FreezeHeader:   viper.GetBool("workbook.freeze-header"),
+		AutoFilter:     viper.GetBool("workbook.auto-filter"),
+		Hyperlinks:     viper.GetBool("workbook.hyperlinks"),
+		MinWidth:       viper.GetFloat64("workbook.min-width"),
+		MaxWidth:       viper.GetFloat64("workbook.max-width"),
+		ColumnWidths:   map[string]float64{},
+		CurrencyNumFmt: viper.GetString("workbook.currency-num-fmt"),
+		WeightNumFmt:   viper.GetString("workbook.weight-num-fmt"),
+		RateNumFmt:     viper.GetString("workbook.rate-num-fmt"),
+	}
+	for header, width := range viper.GetStringMap("workbook.column-widths") {
+		var w float64
+		if _, err := fmt.Sscan(fmt.Sprint(width), &w); err == nil {
+			p.ColumnWidths[strings.ToLower(header)] = w
+		}
+	}
+	return p
+}
+
+// ApplySheet Style the header row, freeze it, add the auto filter and size the columns of the sheet.
+// It should be called after all cells of the sheet are filled.
+func (p *Profile) ApplySheet(file *excelize.File, sheetName string) error {
+	rows, err := file.GetRows(sheetName)
+	if err != nil || len(rows) == 0 {
+		return err
+	}
+	headers := rows[0]
+	if len(headers) == 0 {
+		return nil
+	}
+	lastCol, err := excelize.ColumnNumberToName(len(headers))
+	if err != nil {
+		return err
+	}
+
+	headerStyle := &excelize.Style{Font: &excelize.Font{Bold: p.HeaderBold}}
+	if p.HeaderFill != "" {
+		headerStyle.Fill = excelize.Fill{Type: "pattern", Color: []string{p.HeaderFill}, Pattern: 1}
+	}
+	style, err := file.NewStyle(headerStyle)
+	if err != nil {
+		return err
+	}
+	if err = file.SetCellStyle(sheetName, "A1", lastCol+"1", style); err != nil {
+		return err
+	}
+
+	if p.FreezeHeader {
+		err = file.SetPanes(sheetName, `{"freeze":true,"split":false,"x_split":0,"y_split":1,"top_left_cell":"A2","active_pane":"bottomLeft"}`)
+		if err != nil {
+			return err
+		}
+	}
+	if p.AutoFilter {
+		if err = file.AutoFilter(sheetName, "A1", fmt.Sprintf("%s%d", lastCol, len(rows)), ""); err != nil {
+			return err
+		}
+	}
+
+	// 列宽: 配置的固定宽度优先，否则按内容长度
+	for i, header := range headers {
+		col, _ := excelize.ColumnNumberToName(i + 1)
+		width, ok := p.ColumnWidths[strings.ToLower(header)]
+		if !ok {
+			width = p.contentWidth(rows, i)
+		}
+		if err = file.SetColWidth(sheetName, col, col, width); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// contentWidth The width of the column by the longest cell, within the min and max width
+func (p *Profile) contentWidth(rows [][]string, col int) float64 {
+	width := p.MinWidth
+	for _, row := range rows {
+		if col >= len(row) {
+			continue
+		}
+		// 留出筛选按钮的宽度
+		if w := float64(utf8.RuneCountInString(row[col])) + 3; w > width {
+			width = w
+		}
+	}
+	if p.MaxWidth > 0 && width > p.MaxWidth {
+		width = p.MaxWidth
+	}
+	return width
+}
+
+// SetLink Set the link into the cell, as a clickable hyperlink if the profile enables it.
+// The link can be an URL or a path relative to the workbook.
+func (p *Profile) SetLink(file *excelize.File, sheetName, axis, link string) error {
+	if err := file.SetCellStr(sheetName, axis, link); err != nil {
+		return err
+	}
+	if !p.Hyperlinks || link == "" {
+		return nil
+	}
+	if err := file.SetCellHyperLink(sheetName, axis, link, "External"); err != nil {
+		return err
+	}
+	style, err := file.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "#0563C1", Underline: "single"}})
+	if err != nil {
+		return err
+	}
+	return file.SetCellStyle(sheetName, axis, axis, style)
+}
+
+// SetColumnsNumFmt Set the number format for the data rows(from the second row to the last row) of the columns
+func (p *Profile) SetColumnsNumFmt(file *excelize.File, sheetName, startCol, endCol string, lastRow int, numFmt string) error {
+	if lastRow < 2 {
+		return nil
+	}
+	style, err := file.NewStyle(&excelize.Style{CustomNumFmt: &numFmt})
+	if err != nil {
+		return err
+	}
+	return file.SetCellStyle(sheetName, fmt.Sprintf("%s2", startCol), fmt.Sprintf("%s%d", endCol, lastRow), style)
+}